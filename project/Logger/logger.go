@@ -1,32 +1,221 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"myproject/project/shared"
 	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultRedact - ключи, значения которых скрываются, если в конфиге не задан redact.
+var DefaultRedact = []string{"password", "token", "secret", "authorization"}
+
+const redacted = "[REDACTED]"
+
+// Logger - обёртка над slog.Logger. Info, Debug и Error - *log.Logger
+// для кода, которому нужен стандартный логгер (Printf, Fatalf);
+// они пишут через тот же обработчик с соответствующим уровнем.
 type Logger struct {
 	Info  *log.Logger
 	Debug *log.Logger
 	Error *log.Logger
+	// Access - куда писать строки доступа HTTP: cfg.AccessLog или w из New
+	Access io.Writer
+
+	sl *slog.Logger
+	// level - общий для l и его дочерних логгеров, меняется SetLevel
+	level *slog.LevelVar
+	// files - файлы, открытые New; у дочерних логгеров nil
+	files []*RotatingFile
 }
 
+// NewLogger создаёт логгер с настройками по умолчанию: уровень info, text, stdout.
 func NewLogger() *Logger {
+	l, _ := New(shared.LogConfig{}, os.Stdout)
+	return l
+}
+
+// New создаёт логгер по конфигу. Записи пишутся в cfg.File, если задан путь, иначе в w.
+// Так же выбирается Access: cfg.AccessLog или w.
+func New(cfg shared.LogConfig, w io.Writer) (*Logger, error) {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+
+	redact := cfg.Redact
+	if redact == nil {
+		redact = DefaultRedact
+	}
+	keys := make(map[string]bool, len(redact))
+	for _, k := range redact {
+		keys[strings.ToLower(k)] = true
+	}
+	opts := &slog.HandlerOptions{
+		Level:     levelVar,
+		AddSource: cfg.AddSource,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if keys[strings.ToLower(a.Key)] {
+				return slog.String(a.Key, redacted)
+			}
+			return a
+		},
+	}
+
+	if cfg.Format != "" && cfg.Format != "text" && cfg.Format != "json" {
+		return nil, fmt.Errorf("log format must be text or json, got %q", cfg.Format)
+	}
+	var files []*RotatingFile
+	access := w
+	if cfg.AccessLog.Path != "" {
+		f, err := OpenFile(cfg.AccessLog)
+		if err != nil {
+			return nil, fmt.Errorf("access log file: %w", err)
+		}
+		files, access = append(files, f), f
+	}
+	if cfg.File.Path != "" {
+		f, err := OpenFile(cfg.File)
+		if err != nil {
+			closeAll(files)
+			return nil, fmt.Errorf("log file: %w", err)
+		}
+		files, w = append(files, f), f
+	}
+
+	var h slog.Handler
+	if cfg.Format == "json" {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	l := wrap(slog.New(h))
+	l.Access = access
+	l.level = levelVar
+	l.files = files
+	return l, nil
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return level, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("log level: %w", err)
+	}
+	return level, nil
+}
+
+func wrap(sl *slog.Logger) *Logger {
+	h := sl.Handler()
 	return &Logger{
-		Info:  log.New(os.Stdout, "[INFO] ", log.LstdFlags),                 //время
-		Debug: log.New(os.Stdout, "[DEBUG] ", log.LstdFlags|log.Lshortfile), //время +строка
-		Error: log.New(os.Stderr, "[ERROR] ", log.LstdFlags|log.Lshortfile), //время +строка
+		Info:  slog.NewLogLogger(h, slog.LevelInfo),
+		Debug: slog.NewLogLogger(h, slog.LevelDebug),
+		Error: slog.NewLogLogger(h, slog.LevelError),
+		sl:    sl,
+	}
+}
+
+// With возвращает дочерний логгер, добавляющий к записям request_id,
+// trace_id и span_id из ctx. Если в ctx их нет, возвращается сам l.
+func (l *Logger) With(ctx context.Context) *Logger {
+	var fields []any
+	if id := shared.RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, "request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+	if fields == nil {
+		return l
+	}
+	return l.WithFields(fields...)
+}
+
+// WithFields возвращает дочерний логгер с постоянными полями ключ/значение.
+func (l *Logger) WithFields(args ...any) *Logger {
+	c := wrap(l.sl.With(args...))
+	c.Access = l.Access
+	c.level = l.level
+	return c
+}
+
+// SetLevel меняет минимальный уровень l и всех его дочерних логгеров.
+// Пустая строка означает info.
+func (l *Logger) SetLevel(s string) error {
+	level, err := parseLevel(s)
+	if err != nil {
+		return err
+	}
+	l.level.Set(level)
+	return nil
+}
+
+// Rotate начинает новые файлы лога. Без файлов ничего не делает.
+func (l *Logger) Rotate() error {
+	var errs []error
+	for _, f := range l.files {
+		errs = append(errs, f.Rotate())
 	}
+	return errors.Join(errs...)
 }
 
-func (l *Logger) INFO(msg string) {
-	l.Info.Println(msg)
+// Close закрывает файлы лога, если они есть.
+func (l *Logger) Close() error {
+	return closeAll(l.files)
 }
 
-func (l *Logger) DEBUG(msg string) {
-	l.Debug.Println(msg)
+func closeAll(files []*RotatingFile) error {
+	var errs []error
+	for _, f := range files {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Slog возвращает нижележащий slog.Logger.
+func (l *Logger) Slog() *slog.Logger {
+	return l.sl
+}
+
+// INFO, DEBUG, WARN и ERROR принимают сообщение и необязательные пары ключ/значение.
+func (l *Logger) INFO(msg string, args ...any) {
+	l.log(slog.LevelInfo, msg, args)
+}
+
+func (l *Logger) DEBUG(msg string, args ...any) {
+	l.log(slog.LevelDebug, msg, args)
+}
+
+func (l *Logger) WARN(msg string, args ...any) {
+	l.log(slog.LevelWarn, msg, args)
 }
 
-func (l *Logger) ERROR(msg string) {
-	l.Error.Println(msg)
+func (l *Logger) ERROR(msg string, args ...any) {
+	l.log(slog.LevelError, msg, args)
+}
+
+func (l *Logger) log(level slog.Level, msg string, args []any) {
+	ctx := context.Background()
+	if !l.sl.Enabled(ctx, level) {
+		return
+	}
+	// Пропускаем runtime.Callers, log и INFO/DEBUG/WARN/ERROR, чтобы source указывал на вызывающий код
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	l.sl.Handler().Handle(ctx, r)
 }
@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"myproject/project/shared"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat - метка времени в имени ротированного файла, сортируется как строка.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile - файл лога с ротацией по размеру и возрасту. Ротированные
+// файлы переименовываются в <имя>-<время><расширение>, при необходимости
+// сжимаются gzip, лишние сверх MaxBackups удаляются.
+type RotatingFile struct {
+	cfg shared.FileSinkConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	// mill сериализует сжатие и удаление старых файлов
+	mill sync.Mutex
+}
+
+// OpenFile открывает (или создаёт) файл cfg.Path для дозаписи.
+func OpenFile(cfg shared.FileSinkConfig) (*RotatingFile, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("log file path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &RotatingFile{cfg: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reopen(); err != nil {
+		return 0, err
+	}
+	if f.due(int64(len(p))) {
+		// Неудачная ротация не должна терять запись: пишем в тот файл, что открыт
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			fmt.Fprintf(os.Stderr, "logger: rotate %s: %v\n", f.cfg.Path, err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// due сообщает, нужна ли ротация перед записью n байт. Пустой файл
+// по размеру не ротируется, чтобы слишком длинная запись не зациклила ротацию.
+func (f *RotatingFile) due(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if max := int64(f.cfg.MaxSizeMB) << 20; max > 0 && f.size+n > max {
+		return true
+	}
+	return f.cfg.MaxAge > 0 && time.Since(f.openedAt) >= f.cfg.MaxAge
+}
+
+// Rotate закрывает текущий файл и начинает новый. Вызывается по SIGHUP;
+// если файл переместил logrotate, просто открывается новый по тому же пути.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed || f.file == nil {
+		return f.reopen()
+	}
+	return f.rotate()
+}
+
+// reopen открывает файл заново, если предыдущая ротация не смогла этого сделать.
+func (f *RotatingFile) reopen() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file == nil {
+		return f.open()
+	}
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	backup := f.backupName(time.Now())
+	renameErr := os.Rename(f.cfg.Path, backup)
+	if errors.Is(renameErr, fs.ErrNotExist) {
+		backup, renameErr = "", nil
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	go f.cleanup(backup)
+	return nil
+}
+
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, name := filepath.Split(f.cfg.Path)
+	ext := filepath.Ext(name)
+	return filepath.Join(dir, strings.TrimSuffix(name, ext)+"-"+t.Format(backupTimeFormat)+ext)
+}
+
+// cleanup сжимает только что ротированный файл и удаляет лишние.
+func (f *RotatingFile) cleanup(backup string) {
+	f.mill.Lock()
+	defer f.mill.Unlock()
+
+	if backup != "" && f.cfg.Compress {
+		if err := gzipFile(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: compress %s: %v\n", backup, err)
+		}
+	}
+	if f.cfg.MaxBackups <= 0 {
+		return
+	}
+	backups, err := f.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: list backups of %s: %v\n", f.cfg.Path, err)
+		return
+	}
+	for _, old := range backups[min(f.cfg.MaxBackups, len(backups)):] {
+		if err := os.Remove(old); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: remove %s: %v\n", old, err)
+		}
+	}
+}
+
+// backups возвращает ротированные файлы, от новых к старым.
+func (f *RotatingFile) backups() ([]string, error) {
+	dir, name := filepath.Split(f.cfg.Path)
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		n := e.Name()
+		stamp, ok := strings.CutPrefix(strings.TrimSuffix(n, ".gz"), prefix)
+		if !ok || e.IsDir() || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(stamp, ext)); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(dir, n))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close закрывает файл, дальнейшие записи возвращают os.ErrClosed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
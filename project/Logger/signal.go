@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// RotateOnSIGHUP начинает новые файлы лога при каждом SIGHUP до отмены ctx,
+// например после того, как logrotate переместил текущие.
+func (l *Logger) RotateOnSIGHUP(ctx context.Context) {
+	if len(l.files) == 0 {
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := l.Rotate(); err != nil {
+					l.ERROR(fmt.Sprintf("SIGHUP: log rotation failed: %v", err))
+					continue
+				}
+				l.INFO("SIGHUP: log files rotated")
+			}
+		}
+	}()
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"myproject/project/shared"
+	"net/http"
+	"net/url"
+)
+
+func (cli *Client) BatchCreate(ctx context.Context, tasks []shared.Task, mode string) (*shared.BatchResponse, error) {
+	return cli.batch(ctx, http.MethodPost, tasks, mode)
+}
+
+func (cli *Client) BatchUpdate(ctx context.Context, patches []shared.BatchPatch, mode string) (*shared.BatchResponse, error) {
+	return cli.batch(ctx, http.MethodPatch, patches, mode)
+}
+
+func (cli *Client) BatchDelete(ctx context.Context, ids []int, mode string) (*shared.BatchResponse, error) {
+	return cli.batch(ctx, http.MethodDelete, ids, mode)
+}
+
+func (cli *Client) batch(ctx context.Context, method string, payload any, mode string) (*shared.BatchResponse, error) {
+	log := cli.log.With(ctx)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to marshal batch: %v", err))
+		return nil, err
+	}
+
+	u := fmt.Sprintf("%s/tasks/batch?mode=%s", cli.baseURL(), url.QueryEscape(mode))
+	log.DEBUG(fmt.Sprintf("%s batch request URL: %s, size: %d bytes", method, u, len(body)))
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to create %s batch request: %v", method, err))
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cli.do(req)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("%s batch request failed: %v", method, err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusMultiStatus:
+	case http.StatusBadRequest:
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("%s batch rejected by db-service: %s", method, apiErr.Message))
+		return nil, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	case http.StatusConflict:
+		conflict := conflictError(resp)
+		log.INFO(fmt.Sprintf("%s batch: %s", method, conflict.Msg))
+		return nil, conflict
+	case http.StatusNotFound:
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("%s batch: %s", method, apiErr.Message))
+		return nil, &NotFoundError{Msg: apiErr.Message}
+	default:
+		log.ERROR(fmt.Sprintf("unexpected status code on %s batch: %d", method, resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var result shared.BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+
+	log.INFO(fmt.Sprintf("%s batch executed, %d result(s)", method, len(result.Results)))
+	return &result, nil
+}
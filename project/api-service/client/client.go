@@ -2,13 +2,30 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	logger "myproject/project/Logger"
+	"myproject/project/auth"
+	"myproject/project/health"
+	"myproject/project/metrics"
 	"myproject/project/shared"
+	"myproject/project/tracing"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type NotFoundError struct {
@@ -36,184 +53,699 @@ func (e *ContentTypeError) Error() string {
 	return fmt.Sprintf("unexpected content type: %s", e.Got)
 }
 
+type PreconditionFailedError struct {
+	Msg string
+	// ETag текущей версии задачи, если db-service его вернул
+	ETag string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Msg
+}
+
+// ConflictError - задача нарушает правило уникальности db-service.
+type ConflictError struct {
+	Msg string
+	// TaskID - id существующей задачи, с которой возник конфликт
+	TaskID int
+}
+
+func (e *ConflictError) Error() string {
+	return e.Msg
+}
+
+// conflictError разбирает тело ответа 409.
+func conflictError(resp *http.Response) *ConflictError {
+	apiErr := decodeError(resp)
+	taskID, _ := apiErr.Details["task_id"].(float64)
+	return &ConflictError{Msg: apiErr.Message, TaskID: int(taskID)}
+}
+
+// decodeError читает ErrorResponse из тела ответа. Если тело не в этом
+// формате, сообщением становится сам текст тела.
+func decodeError(resp *http.Response) shared.ErrorResponse {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var apiErr shared.ErrorResponse
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Message == "" {
+		apiErr = shared.ErrorResponse{Code: shared.CodeForStatus(resp.StatusCode), Message: strings.TrimSpace(string(body))}
+	}
+	return apiErr
+}
+
+// IdempotencyKeyReusedError - ключ идемпотентности уже использован с другим телом запроса.
+type IdempotencyKeyReusedError struct {
+	Msg string
+}
+
+func (e *IdempotencyKeyReusedError) Error() string {
+	return e.Msg
+}
+
+type ValidationError struct {
+	Msg string
+	// Details - подробности ошибки из ответа db-service
+	Details map[string]any
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+// Options - необязательные настройки клиента.
+type Options struct {
+	// Signer подписывает каждый запрос к db-service (HMAC общим секретом)
+	Signer *auth.Signer
+	// TLS включает mTLS при обращении к db-service по https
+	TLS *tls.Config
+	// Retry - повторы идемпотентных запросов, нулевые поля заменяются значениями по умолчанию
+	Retry shared.RetryConfig
+	// Breaker - circuit breaker для db-service
+	Breaker shared.BreakerConfig
+	// Metrics - метрики запросов к db-service, nil отключает
+	Metrics *metrics.Upstream
+}
+
 type Client struct {
 	httpClient *http.Client
-	baseURL    string
-	log        *logger.Logger
+	// settings - перезагружаемые настройки, общие для всех копий Client (As)
+	settings *atomic.Pointer[settings]
+	log      *logger.Logger
+	signer   *auth.Signer
+	breaker  *breaker
+	metrics  *metrics.Upstream
+	// userID передаётся в db-service в заголовке X-User-ID
+	userID int
+	// allOwners снимает в db-service ограничение задач владельцем (X-Owner-Scope)
+	allOwners bool
 }
 
-func NewClient(baseURL string, logger logger.Logger) *Client {
-	return &Client{
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-		baseURL:    baseURL,
+// NewClient создаёт клиент db-service. Общего таймаута у http.Client нет:
+// время запроса ограничивается дедлайном контекста, переданного в метод.
+func NewClient(baseURL string, logger logger.Logger, opts Options) *Client {
+	httpClient := &http.Client{}
+	if opts.TLS != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = opts.TLS
+		httpClient.Transport = transport
+	}
+	cli := &Client{
+		httpClient: httpClient,
+		settings:   new(atomic.Pointer[settings]),
 		log:        &logger,
+		signer:     opts.Signer,
+		breaker:    newBreaker(opts.Breaker),
+		metrics:    opts.Metrics,
+	}
+	cli.settings.Store(&settings{baseURL: baseURL, retry: opts.Retry.WithDefaults()})
+	return cli
+}
+
+// settings - адрес db-service и повторы, которые меняются без перезапуска.
+type settings struct {
+	baseURL string
+	retry   shared.RetryConfig
+}
+
+// Reconfigure применяет новые адрес db-service, повторы и настройки circuit breaker.
+// Запросы, которые уже выполняются, завершаются со старыми настройками.
+// При смене адреса состояние circuit breaker сбрасывается.
+func (cli *Client) Reconfigure(baseURL string, retry shared.RetryConfig, breaker shared.BreakerConfig) {
+	old := cli.settings.Swap(&settings{baseURL: baseURL, retry: retry.WithDefaults()})
+	cli.breaker.configure(breaker, old.baseURL != baseURL)
+}
+
+func (cli *Client) baseURL() string {
+	return cli.settings.Load().baseURL
+}
+
+// As возвращает копию клиента, выполняющую запросы от имени пользователя userID.
+func (cli *Client) As(userID int) *Client {
+	c := *cli
+	c.userID = userID
+	c.allOwners = false
+	return &c
+}
+
+// AllOwners возвращает копию клиента, запросы которой не ограничены задачами userID.
+func (cli *Client) AllOwners() *Client {
+	c := *cli
+	c.allOwners = true
+	return &c
+}
+
+// do выполняет запрос к db-service. Идемпотентные запросы повторяются
+// с экспоненциальной задержкой при сетевых ошибках и ответах 502/503/504.
+// Пока открыт circuit breaker, запросы не выполняются.
+func (cli *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	log := cli.log.With(ctx)
+	if cli.userID > 0 {
+		req.Header.Set(shared.UserIDHeader, strconv.Itoa(cli.userID))
+	}
+	if cli.allOwners {
+		req.Header.Set(shared.OwnerScopeHeader, shared.OwnerScopeAll)
+	}
+	// Тот же X-Request-ID, что и у входящего запроса, связывает логи обоих сервисов
+	if id := shared.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(shared.RequestIDHeader, id)
 	}
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	set := cli.settings.Load()
+	attempts := 1
+	if idempotent(req) {
+		attempts = set.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		if err := cli.breaker.allow(); err != nil {
+			cli.metrics.Error(req.Method, req.URL.Path, metrics.ReasonCircuitOpen)
+			log.ERROR(fmt.Sprintf("%s %s not sent: %v", req.Method, req.URL.Path, err))
+			return nil, err
+		}
+
+		// Каждая попытка - отдельный клиентский спан, db-service продолжает трассу из traceparent
+		route := metrics.Route(req.URL.Path)
+		spanCtx, span := tracing.Tracer().Start(ctx, req.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLFull(req.URL.String()),
+				attribute.Int("http.request.resend_count", attempt-1),
+			),
+		)
+		r := req.Clone(spanCtx)
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		otel.GetTextMapPropagator().Inject(spanCtx, propagation.HeaderCarrier(r.Header))
+		// Подпись на каждую попытку новая: nonce нельзя использовать повторно
+		if cli.signer != nil {
+			cli.signer.Sign(r, body)
+		}
+
+		start := time.Now()
+		resp, err := cli.httpClient.Do(r)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		}
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case status >= http.StatusInternalServerError:
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+		cli.metrics.Observe(req.Method, req.URL.Path, status, time.Since(start))
+		switch {
+		case err != nil && ctx.Err() != nil:
+			cli.metrics.Error(req.Method, req.URL.Path, metrics.ReasonCanceled)
+			cli.breaker.release()
+			return nil, ctx.Err()
+		case err == nil && !retryableStatus(resp.StatusCode):
+			if resp.StatusCode >= http.StatusInternalServerError {
+				cli.metrics.Error(req.Method, req.URL.Path, metrics.ReasonStatus)
+			}
+			cli.breaker.success()
+			return resp, nil
+		}
+		if err != nil {
+			cli.metrics.Error(req.Method, req.URL.Path, metrics.ReasonNetwork)
+		} else {
+			cli.metrics.Error(req.Method, req.URL.Path, metrics.ReasonStatus)
+		}
+
+		if cli.breaker.failure() {
+			log.ERROR(fmt.Sprintf("circuit breaker opened for %s", set.baseURL))
+		}
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if attempt >= attempts {
+			return nil, &UpstreamUnavailableError{Msg: "db-service unavailable", RetryAfter: cli.breaker.retryAfter(), Err: err}
+		}
+
+		delay := backoff(set.retry, attempt)
+		log.INFO(fmt.Sprintf("%s %s attempt %d/%d failed: %v, retrying in %v", req.Method, req.URL.Path, attempt, attempts, err, delay))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (cli *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cli.do(req)
+}
+
+func (cli *Client) post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return cli.do(req)
 }
 
-func (cli *Client) GetTask(id int) (*shared.Task, error) {
-	url := fmt.Sprintf("%s/tasks/%d", cli.baseURL, id)
-	cli.log.DEBUG(fmt.Sprintf("GET request URL: %s", url)) // DEBUG: формирование запроса
+func (cli *Client) GetTask(ctx context.Context, id int) (*shared.Task, error) {
+	log := cli.log.With(ctx)
+	url := fmt.Sprintf("%s/tasks/%d", cli.baseURL(), id)
+	log.DEBUG(fmt.Sprintf("GET request URL: %s", url)) // DEBUG: формирование запроса
 
-	resp, err := cli.httpClient.Get(url)
+	resp, err := cli.get(ctx, url)
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("GET request failed: %v", err))
+		log.ERROR(fmt.Sprintf("GET request failed: %v", err))
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	contentType := resp.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "application/json") {
-		cli.log.ERROR(fmt.Sprintf("unexpected content type: %s", contentType))
+		log.ERROR(fmt.Sprintf("unexpected content type: %s", contentType))
 		return nil, &ContentTypeError{Got: contentType}
 	}
 
 	if resp.StatusCode == http.StatusNotFound {
-		cli.log.INFO(fmt.Sprintf("task %d not found", id)) // INFO: ожидаемое отсутствие задачи
+		log.INFO(fmt.Sprintf("task %d not found", id)) // INFO: ожидаемое отсутствие задачи
 		return nil, &NotFoundError{Msg: fmt.Sprintf("task %d not found", id)}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		cli.log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+		log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
 		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
 	}
 
 	var task shared.Task
 	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
-		cli.log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
 		return nil, err
 	}
 
-	cli.log.INFO(fmt.Sprintf("task %d retrieved successfully", task.ID)) // INFO: успешное выполнение
+	log.INFO(fmt.Sprintf("task %d retrieved successfully", task.ID)) // INFO: успешное выполнение
 	return &task, nil
 }
 
-func (cli *Client) PostTask(task shared.Task) (int64, error) {
+// PostTask создаёт задачу. Непустой idempotencyKey передаётся в db-service
+// в заголовке Idempotency-Key, и тогда запрос повторяется при сбоях.
+func (cli *Client) PostTask(ctx context.Context, task shared.Task, idempotencyKey string) (int64, error) {
+	log := cli.log.With(ctx)
 	body, err := json.Marshal(task)
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("failed to marshal task: %v", err))
+		log.ERROR(fmt.Sprintf("failed to marshal task: %v", err))
 		return 0, err
 	}
 
-	url := fmt.Sprintf("%s/tasks", cli.baseURL)
-	cli.log.DEBUG(fmt.Sprintf("POST request URL: %s, body: %s", url, string(body)))
+	url := fmt.Sprintf("%s/tasks", cli.baseURL())
+	log.DEBUG("POST request", "url", url, "idempotency_key", idempotencyKey, "body_bytes", len(body))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to create POST request: %v", err))
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set(shared.IdempotencyKeyHeader, idempotencyKey)
+	}
 
-	resp, err := cli.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	resp, err := cli.do(req)
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("POST request failed: %v", err))
+		log.ERROR(fmt.Sprintf("POST request failed: %v", err))
 		return 0, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("POST rejected by db-service: %s", apiErr.Message))
+		return 0, &IdempotencyKeyReusedError{Msg: apiErr.Message}
+	}
+
+	if resp.StatusCode == http.StatusBadRequest {
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("POST rejected by db-service: %s", apiErr.Message))
+		return 0, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	}
+
 	contentType := resp.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "application/json") {
-		cli.log.ERROR(fmt.Sprintf("unexpected content type: %s", contentType))
+		log.ERROR(fmt.Sprintf("unexpected content type: %s", contentType))
 		return 0, &ContentTypeError{Got: contentType}
 	}
 
 	if resp.StatusCode == http.StatusConflict {
-		cli.log.INFO("task already exists") // INFO: ожидаемая конфликтная ситуация
-		return 0, &StatusError{Code: resp.StatusCode, Msg: "task already exists"}
+		conflict := conflictError(resp)
+		log.INFO(fmt.Sprintf("task already exists, conflicting ID: %d", conflict.TaskID)) // INFO: ожидаемая конфликтная ситуация
+		return 0, conflict
 	}
 
 	if resp.StatusCode != http.StatusCreated {
-		cli.log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+		log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
 		return 0, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
 	}
 
 	var ID shared.IDResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ID); err != nil {
-		cli.log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
 		return 0, err
 	}
 
-	cli.log.INFO(fmt.Sprintf("task created successfully, ID: %d", ID.ID))
+	if resp.Header.Get("Idempotent-Replayed") == "true" {
+		log.INFO(fmt.Sprintf("task creation replayed by idempotency key, ID: %d", ID.ID))
+		return ID.ID, nil
+	}
+	log.INFO(fmt.Sprintf("task created successfully, ID: %d", ID.ID))
 	return ID.ID, nil
 }
 
-func (cli *Client) GetAllTasks() ([]shared.Task, error) {
-	url := fmt.Sprintf("%s/tasks", cli.baseURL)
-	cli.log.DEBUG(fmt.Sprintf("GET ALL request URL: %s", url))
+func (cli *Client) GetAllTasks(ctx context.Context, filter shared.TaskFilter) (*shared.TaskPage, error) {
+	return cli.listTasks(ctx, "/tasks", filter)
+}
+
+// GetTrash возвращает страницу задач из корзины.
+func (cli *Client) GetTrash(ctx context.Context, filter shared.TaskFilter) (*shared.TaskPage, error) {
+	return cli.listTasks(ctx, "/tasks/trash", filter)
+}
 
-	resp, err := cli.httpClient.Get(url)
+func (cli *Client) listTasks(ctx context.Context, path string, filter shared.TaskFilter) (*shared.TaskPage, error) {
+	log := cli.log.With(ctx)
+	url := cli.baseURL() + path
+	if q := filter.Values().Encode(); q != "" {
+		url += "?" + q
+	}
+	log.DEBUG(fmt.Sprintf("GET ALL request URL: %s", url))
+
+	resp, err := cli.get(ctx, url)
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("GET ALL request failed: %v", err))
+		log.ERROR(fmt.Sprintf("GET ALL request failed: %v", err))
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusBadRequest {
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("GET ALL rejected by db-service: %s", apiErr.Message))
+		return nil, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		cli.log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+		log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
 		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
 	}
 
-	var tasks []shared.Task
-	if err := json.NewDecoder(resp.Body).Decode(&tasks); err != nil {
-		cli.log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+	var page shared.TaskPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
 		return nil, err
 	}
 
-	cli.log.INFO(fmt.Sprintf("retrieved %d tasks successfully", len(tasks)))
-	return tasks, nil
+	log.INFO(fmt.Sprintf("retrieved %d of %d tasks successfully", len(page.Tasks), page.Total))
+	return &page, nil
 }
 
-func (cli *Client) Delete(id int) error {
-	url := fmt.Sprintf("%s/tasks/%d", cli.baseURL, id)
-	cli.log.DEBUG(fmt.Sprintf("DELETE request URL: %s", url))
+func (cli *Client) Search(ctx context.Context, q shared.SearchQuery) (*shared.SearchResponse, error) {
+	log := cli.log.With(ctx)
+	url := fmt.Sprintf("%s/tasks/search?%s", cli.baseURL(), q.Values().Encode())
+	log.DEBUG(fmt.Sprintf("SEARCH request URL: %s", url))
 
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	resp, err := cli.get(ctx, url)
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("failed to create DELETE request: %v", err))
+		log.ERROR(fmt.Sprintf("SEARCH request failed: %v", err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("SEARCH rejected by db-service: %s", apiErr.Message))
+		return nil, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var result shared.SearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+
+	log.INFO(fmt.Sprintf("search returned %d result(s)", len(result.Results)))
+	return &result, nil
+}
+
+func (cli *Client) Delete(ctx context.Context, id int) error {
+	return cli.delete(ctx, id, false)
+}
+
+// Purge удаляет задачу безвозвратно (DELETE /tasks/{id}?purge=true).
+func (cli *Client) Purge(ctx context.Context, id int) error {
+	return cli.delete(ctx, id, true)
+}
+
+func (cli *Client) delete(ctx context.Context, id int, purge bool) error {
+	log := cli.log.With(ctx)
+	url := fmt.Sprintf("%s/tasks/%d", cli.baseURL(), id)
+	if purge {
+		url += "?purge=true"
+	}
+	log.DEBUG(fmt.Sprintf("DELETE request URL: %s", url))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to create DELETE request: %v", err))
 		return err
 	}
 
-	resp, err := cli.httpClient.Do(req)
+	resp, err := cli.do(req)
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("DELETE request failed: %v", err))
+		log.ERROR(fmt.Sprintf("DELETE request failed: %v", err))
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		cli.log.INFO(fmt.Sprintf("task %d not found for deletion", id))
+		log.INFO(fmt.Sprintf("task %d not found for deletion", id))
 		return &NotFoundError{Msg: fmt.Sprintf("task %d not found", id)}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		cli.log.ERROR(fmt.Sprintf("unexpected status code on DELETE: %d", resp.StatusCode))
+		log.ERROR(fmt.Sprintf("unexpected status code on DELETE: %d", resp.StatusCode))
 		return &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
 	}
 
-	cli.log.INFO(fmt.Sprintf("task %d deleted successfully", id))
+	log.INFO(fmt.Sprintf("task %d deleted successfully, purge=%t", id, purge))
 	return nil
 }
 
-func (cli *Client) Update(id int) error {
-	url := fmt.Sprintf("%s/tasks/%d", cli.baseURL, id)
-	cli.log.DEBUG(fmt.Sprintf("PATCH request URL: %s", url))
+func (cli *Client) Update(ctx context.Context, id int, patch shared.TaskPatch) (*shared.Task, error) {
+	log := cli.log.With(ctx)
+	body, err := json.Marshal(patch)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to marshal patch: %v", err))
+		return nil, err
+	}
 
-	req, err := http.NewRequest(http.MethodPatch, url, nil)
+	url := fmt.Sprintf("%s/tasks/%d", cli.baseURL(), id)
+	log.DEBUG("PATCH request", "url", url, "task_id", id, "body_bytes", len(body))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(body))
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("failed to create PATCH request: %v", err))
-		return err
+		log.ERROR(fmt.Sprintf("failed to create PATCH request: %v", err))
+		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := cli.httpClient.Do(req)
+	resp, err := cli.do(req)
 	if err != nil {
-		cli.log.ERROR(fmt.Sprintf("PATCH request failed: %v", err))
-		return err
+		log.ERROR(fmt.Sprintf("PATCH request failed: %v", err))
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		cli.log.INFO(fmt.Sprintf("task %d not found for update", id))
-		return &NotFoundError{Msg: fmt.Sprintf("task %d not found", id)}
+		log.INFO(fmt.Sprintf("task %d not found for update", id))
+		return nil, &NotFoundError{Msg: fmt.Sprintf("task %d not found", id)}
 	}
 
-	if resp.StatusCode != http.StatusNoContent {
-		cli.log.ERROR(fmt.Sprintf("unexpected status code on PATCH: %d", resp.StatusCode))
-		return &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	if resp.StatusCode == http.StatusConflict {
+		conflict := conflictError(resp)
+		log.INFO(fmt.Sprintf("task %d conflicts with task %d", id, conflict.TaskID))
+		return nil, conflict
 	}
 
-	cli.log.INFO(fmt.Sprintf("task %d updated successfully", id))
-	return nil
+	if resp.StatusCode == http.StatusBadRequest {
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("PATCH rejected by db-service: %s", apiErr.Message))
+		return nil, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.ERROR(fmt.Sprintf("unexpected status code on PATCH: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var task shared.Task
+	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+
+	log.INFO(fmt.Sprintf("task %d updated successfully", id))
+	return &task, nil
+}
+
+// ReplaceTask выполняет PUT /tasks/{id}. Непустой ifMatch передаётся
+// в заголовке If-Match, устаревшая версия приводит к PreconditionFailedError.
+func (cli *Client) ReplaceTask(ctx context.Context, id int, task shared.Task, ifMatch string) (*shared.Task, error) {
+	log := cli.log.With(ctx)
+	body, err := json.Marshal(task)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to marshal task: %v", err))
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/tasks/%d", cli.baseURL(), id)
+	log.DEBUG("PUT request", "url", url, "task_id", id, "if_match", ifMatch, "body_bytes", len(body))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to create PUT request: %v", err))
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	resp, err := cli.do(req)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("PUT request failed: %v", err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		log.INFO(fmt.Sprintf("task %d not found for replace", id))
+		return nil, &NotFoundError{Msg: fmt.Sprintf("task %d not found", id)}
+	case http.StatusPreconditionFailed:
+		log.INFO(fmt.Sprintf("task %d was modified concurrently", id))
+		return nil, &PreconditionFailedError{
+			Msg:  fmt.Sprintf("task %d was modified by another request", id),
+			ETag: resp.Header.Get("ETag"),
+		}
+	case http.StatusConflict:
+		conflict := conflictError(resp)
+		log.INFO(fmt.Sprintf("task %d conflicts with task %d", id, conflict.TaskID))
+		return nil, conflict
+	case http.StatusBadRequest:
+		apiErr := decodeError(resp)
+		log.INFO(fmt.Sprintf("PUT rejected by db-service: %s", apiErr.Message))
+		return nil, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	case http.StatusOK:
+	default:
+		log.ERROR(fmt.Sprintf("unexpected status code on PUT: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var updated shared.Task
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+
+	log.INFO(fmt.Sprintf("task %d replaced successfully, version=%d", id, updated.Version))
+	return &updated, nil
+}
+
+// Restore возвращает задачу из корзины.
+func (cli *Client) Restore(ctx context.Context, id int) (*shared.Task, error) {
+	log := cli.log.With(ctx)
+	url := fmt.Sprintf("%s/tasks/%d/restore", cli.baseURL(), id)
+	log.DEBUG(fmt.Sprintf("POST request URL: %s", url))
+
+	resp, err := cli.post(ctx, url, "application/json", nil)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("restore request failed: %v", err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		log.INFO(fmt.Sprintf("task %d not found in trash", id))
+		return nil, &NotFoundError{Msg: fmt.Sprintf("task %d not found in trash", id)}
+	}
+
+	if resp.StatusCode == http.StatusConflict {
+		conflict := conflictError(resp)
+		log.INFO(fmt.Sprintf("task %d conflicts with task %d", id, conflict.TaskID))
+		return nil, conflict
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.ERROR(fmt.Sprintf("unexpected status code on restore: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var task shared.Task
+	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+
+	log.INFO(fmt.Sprintf("task %d restored successfully", id))
+	return &task, nil
+}
+
+// Ping проверяет готовность db-service по GET /readyz. Запрос выполняется
+// один раз, без повторов и circuit breaker, чтобы проверки не влияли на трафик.
+func (cli *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cli.baseURL()+"/readyz", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var ready health.Response
+	if err := json.NewDecoder(resp.Body).Decode(&ready); err != nil {
+		return &StatusError{Code: resp.StatusCode, Msg: "db-service not ready"}
+	}
+	var failed []string
+	for name, check := range ready.Checks {
+		if check.Status != health.StatusOK {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, check.Error))
+		}
+	}
+	sort.Strings(failed)
+	return &StatusError{Code: resp.StatusCode, Msg: "db-service not ready: " + strings.Join(failed, "; ")}
 }
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"myproject/project/shared"
+)
+
+// UpstreamUnavailableError - db-service недоступен: открыт circuit breaker
+// или исчерпаны повторы при сетевых ошибках.
+type UpstreamUnavailableError struct {
+	Msg string
+	// RetryAfter - через сколько имеет смысл повторить запрос
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UpstreamUnavailableError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Msg, e.Err)
+	}
+	return e.Msg
+}
+
+func (e *UpstreamUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// idempotent сообщает, можно ли безопасно повторить запрос.
+// POST повторяется только с ключом идемпотентности.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return req.Header.Get("Idempotency-Key") != ""
+	}
+	return false
+}
+
+// retryableStatus - ответы, которые означают временную недоступность upstream.
+func retryableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// backoff - экспоненциальная задержка перед попыткой attempt+1 с полным джиттером.
+func backoff(cfg shared.RetryConfig, attempt int) time.Duration {
+	d := cfg.MaxDelay
+	if attempt < 32 {
+		if exp := cfg.BaseDelay << (attempt - 1); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	return rand.N(d) + 1
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker - circuit breaker одного upstream. Общий для всех копий Client (As).
+type breaker struct {
+	mu       sync.Mutex
+	cfg      shared.BreakerConfig
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// probing - в полуоткрытом состоянии уже выполняется пробный запрос
+	probing bool
+}
+
+func newBreaker(cfg shared.BreakerConfig) *breaker {
+	return &breaker{cfg: cfg.WithDefaults()}
+}
+
+// configure меняет пороги breaker. reset закрывает его и обнуляет счётчик ошибок.
+func (b *breaker) configure(cfg shared.BreakerConfig, reset bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg.WithDefaults()
+	if reset {
+		b.state = breakerClosed
+		b.failures = 0
+		b.probing = false
+	}
+}
+
+// allow возвращает UpstreamUnavailableError, если запрос выполнять нельзя.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		left := b.cfg.OpenTimeout - time.Since(b.openedAt)
+		if left > 0 {
+			return &UpstreamUnavailableError{Msg: "db-service unavailable: circuit open", RetryAfter: left}
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return &UpstreamUnavailableError{Msg: "db-service unavailable: circuit half-open", RetryAfter: time.Second}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// failure возвращает true, если после этой ошибки breaker открылся.
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		opened := b.state != breakerOpen
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		return opened
+	}
+	return false
+}
+
+// retryAfter - через сколько breaker снова пропустит запрос: остаток интервала,
+// на который он открыт, но не меньше секунды.
+func (b *breaker) retryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen {
+		if left := b.cfg.OpenTimeout - time.Since(b.openedAt); left > time.Second {
+			return left
+		}
+	}
+	return time.Second
+}
+
+// release освобождает пробный запрос, результат которого ничего не говорит
+// о состоянии upstream (например, запрос отменил клиент).
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"myproject/project/shared"
+)
+
+// step - одно событие для breaker и ожидаемое состояние после него.
+type step struct {
+	event string // allow, success, failure, release, elapse
+	// allowed - ожидаемый результат allow
+	allowed bool
+	// opened - ожидаемый результат failure
+	opened bool
+	state  breakerState
+}
+
+func TestBreakerStateMachine(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens after threshold",
+			steps: []step{
+				{event: "allow", allowed: true, state: breakerClosed},
+				{event: "failure", state: breakerClosed},
+				{event: "failure", state: breakerClosed},
+				{event: "failure", opened: true, state: breakerOpen},
+				{event: "allow", allowed: false, state: breakerOpen},
+			},
+		},
+		{
+			name: "success resets failure count",
+			steps: []step{
+				{event: "failure", state: breakerClosed},
+				{event: "failure", state: breakerClosed},
+				{event: "success", state: breakerClosed},
+				{event: "failure", state: breakerClosed},
+				{event: "failure", state: breakerClosed},
+				{event: "allow", allowed: true, state: breakerClosed},
+			},
+		},
+		{
+			name: "half-open probe succeeds",
+			steps: []step{
+				{event: "failure"}, {event: "failure"},
+				{event: "failure", opened: true, state: breakerOpen},
+				{event: "elapse", state: breakerOpen},
+				{event: "allow", allowed: true, state: breakerHalfOpen},
+				{event: "allow", allowed: false, state: breakerHalfOpen},
+				{event: "success", state: breakerClosed},
+				{event: "allow", allowed: true, state: breakerClosed},
+			},
+		},
+		{
+			name: "half-open probe fails",
+			steps: []step{
+				{event: "failure"}, {event: "failure"},
+				{event: "failure", opened: true, state: breakerOpen},
+				{event: "elapse", state: breakerOpen},
+				{event: "allow", allowed: true, state: breakerHalfOpen},
+				{event: "failure", opened: true, state: breakerOpen},
+				{event: "allow", allowed: false, state: breakerOpen},
+			},
+		},
+		{
+			name: "released probe lets another through",
+			steps: []step{
+				{event: "failure"}, {event: "failure"},
+				{event: "failure", opened: true, state: breakerOpen},
+				{event: "elapse", state: breakerOpen},
+				{event: "allow", allowed: true, state: breakerHalfOpen},
+				{event: "release", state: breakerHalfOpen},
+				{event: "allow", allowed: true, state: breakerHalfOpen},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(shared.BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute})
+			for i, s := range tt.steps {
+				switch s.event {
+				case "allow":
+					err := b.allow()
+					if (err == nil) != s.allowed {
+						t.Fatalf("step %d: allow() = %v, want allowed=%v", i, err, s.allowed)
+					}
+					var ue *UpstreamUnavailableError
+					if err != nil && (!errors.As(err, &ue) || ue.RetryAfter <= 0) {
+						t.Fatalf("step %d: allow() = %#v, want UpstreamUnavailableError with RetryAfter", i, err)
+					}
+				case "success":
+					b.success()
+				case "failure":
+					if got := b.failure(); got != s.opened {
+						t.Fatalf("step %d: failure() = %v, want %v", i, got, s.opened)
+					}
+				case "release":
+					b.release()
+				case "elapse":
+					b.openedAt = b.openedAt.Add(-b.cfg.OpenTimeout)
+				}
+				if b.state != s.state {
+					t.Fatalf("step %d (%s): state = %d, want %d", i, s.event, b.state, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerConfigure(t *testing.T) {
+	b := newBreaker(shared.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	b.failure()
+
+	b.configure(shared.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}, false)
+	if b.state != breakerOpen {
+		t.Fatalf("configure without reset changed state to %d", b.state)
+	}
+
+	b.configure(shared.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}, true)
+	if b.state != breakerClosed || b.failures != 0 {
+		t.Fatalf("after reset state = %d, failures = %d", b.state, b.failures)
+	}
+	if b.failure() {
+		t.Fatal("opened below new threshold")
+	}
+	if !b.failure() {
+		t.Fatal("did not open at new threshold")
+	}
+}
+
+func TestBreakerRetryAfter(t *testing.T) {
+	b := newBreaker(shared.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	if got := b.retryAfter(); got != time.Second {
+		t.Fatalf("closed: retryAfter = %v, want 1s", got)
+	}
+	b.failure()
+	if got := b.retryAfter(); got <= 50*time.Second || got > time.Minute {
+		t.Fatalf("just opened: retryAfter = %v, want close to 1m", got)
+	}
+	b.openedAt = b.openedAt.Add(-40 * time.Second)
+	if got := b.retryAfter(); got <= 10*time.Second || got > 20*time.Second {
+		t.Fatalf("after 40s: retryAfter = %v, want about 20s", got)
+	}
+	b.openedAt = b.openedAt.Add(-time.Minute)
+	if got := b.retryAfter(); got != time.Second {
+		t.Fatalf("expired: retryAfter = %v, want 1s", got)
+	}
+}
+
+func TestBreakerDefaults(t *testing.T) {
+	b := newBreaker(shared.BreakerConfig{})
+	if b.cfg.FailureThreshold != 5 || b.cfg.OpenTimeout != 30*time.Second {
+		t.Fatalf("defaults = %+v", b.cfg)
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	tests := []struct {
+		method string
+		key    string
+		want   bool
+	}{
+		{http.MethodGet, "", true},
+		{http.MethodPut, "", true},
+		{http.MethodDelete, "", true},
+		{http.MethodPost, "", false},
+		{http.MethodPost, "k1", true},
+		{http.MethodPatch, "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/tasks", nil)
+		if tt.key != "" {
+			req.Header.Set("Idempotency-Key", tt.key)
+		}
+		if got := idempotent(req); got != tt.want {
+			t.Errorf("idempotent(%s, key=%q) = %v, want %v", tt.method, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	cfg := shared.RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 40; attempt++ {
+		limit := cfg.MaxDelay
+		if attempt < 5 {
+			limit = cfg.BaseDelay << (attempt - 1)
+		}
+		for range 20 {
+			if d := backoff(cfg, attempt); d <= 0 || d > limit {
+				t.Fatalf("backoff(%d) = %s, want (0, %s]", attempt, d, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"myproject/project/shared"
+	"net/http"
+)
+
+type UnauthorizedError struct {
+	Msg string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Msg
+}
+
+func (cli *Client) Register(ctx context.Context, creds shared.Credentials) (*shared.User, error) {
+	return cli.postCredentials(ctx, "/users", creds, http.StatusCreated)
+}
+
+func (cli *Client) Authenticate(ctx context.Context, creds shared.Credentials) (*shared.User, error) {
+	return cli.postCredentials(ctx, "/users/authenticate", creds, http.StatusOK)
+}
+
+func (cli *Client) postCredentials(ctx context.Context, path string, creds shared.Credentials, want int) (*shared.User, error) {
+	log := cli.log.With(ctx)
+	body, err := json.Marshal(creds)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to marshal credentials: %v", err))
+		return nil, err
+	}
+
+	url := cli.baseURL() + path
+	// тело не логируется: в нём пароль
+	log.DEBUG(fmt.Sprintf("POST request URL: %s, username: %s", url, creds.Username))
+
+	resp, err := cli.post(ctx, url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("POST request failed: %v", err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case want:
+	case http.StatusBadRequest:
+		apiErr := decodeError(resp)
+		return nil, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	case http.StatusConflict:
+		log.INFO(fmt.Sprintf("user %q already exists", creds.Username))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "user already exists"}
+	case http.StatusUnauthorized:
+		log.INFO(fmt.Sprintf("authentication failed for %q", creds.Username))
+		return nil, &UnauthorizedError{Msg: "invalid username or password"}
+	default:
+		log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var user shared.User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (cli *Client) ListUsers(ctx context.Context) ([]shared.User, error) {
+	log := cli.log.With(ctx)
+	url := cli.baseURL() + "/users"
+	log.DEBUG(fmt.Sprintf("GET request URL: %s", url))
+
+	resp, err := cli.get(ctx, url)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("GET request failed: %v", err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var users []shared.User
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("retrieved %d users successfully", len(users)))
+	return users, nil
+}
+
+func (cli *Client) GetUser(ctx context.Context, userID int) (*shared.User, error) {
+	log := cli.log.With(ctx)
+	url := fmt.Sprintf("%s/users/%d", cli.baseURL(), userID)
+	log.DEBUG(fmt.Sprintf("GET request URL: %s", url))
+
+	resp, err := cli.get(ctx, url)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("GET request failed: %v", err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, &NotFoundError{Msg: fmt.Sprintf("user %d not found", userID)}
+	default:
+		log.ERROR(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var user shared.User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (cli *Client) SetUserRole(ctx context.Context, userID int, role string) (*shared.User, error) {
+	log := cli.log.With(ctx)
+	body, err := json.Marshal(shared.RoleRequest{Role: role})
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to marshal role: %v", err))
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/users/%d/role", cli.baseURL(), userID)
+	log.DEBUG("PUT request", "url", url, "user_id", userID, "body_bytes", len(body))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("failed to create PUT request: %v", err))
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cli.do(req)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("PUT request failed: %v", err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest:
+		apiErr := decodeError(resp)
+		return nil, &ValidationError{Msg: apiErr.Message, Details: apiErr.Details}
+	case http.StatusNotFound:
+		return nil, &NotFoundError{Msg: fmt.Sprintf("user %d not found", userID)}
+	default:
+		log.ERROR(fmt.Sprintf("unexpected status code on PUT: %d", resp.StatusCode))
+		return nil, &StatusError{Code: resp.StatusCode, Msg: "unexpected status"}
+	}
+
+	var user shared.User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		log.ERROR(fmt.Sprintf("failed to decode JSON: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("user %d role set to %s", user.ID, user.Role))
+	return &user, nil
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	logger "myproject/project/Logger"
+	"myproject/project/auth"
+	"net/http"
+)
+
+// AdminHandlers - служебные эндпоинты, доступные только администраторам.
+type AdminHandlers struct {
+	config http.Handler
+	log    *logger.Logger
+	policy *auth.Policy
+}
+
+// NewAdminHandler принимает config - обработчик, отдающий активный конфиг.
+func NewAdminHandler(config http.Handler, log *logger.Logger, policy *auth.Policy) *AdminHandlers {
+	return &AdminHandlers{config, log, policy}
+}
+
+func (h *AdminHandlers) Config(w http.ResponseWriter, r *http.Request) {
+	if err := h.policy.Authorize(r.Context(), auth.ActionViewConfig); err != nil {
+		writeForbidden(w, r, err)
+		return
+	}
+	h.log.With(r.Context()).INFO("Config handler executed successfully")
+	h.config.ServeHTTP(w, r)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	logger "myproject/project/Logger"
+	"myproject/project/api-service/client"
+	"myproject/project/api-service/service"
+	"myproject/project/auth"
+	"myproject/project/shared"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type AuthHandlers struct {
+	service *service.AuthService
+	log     *logger.Logger
+	policy  *auth.Policy
+}
+
+func NewAuthHandler(service *service.AuthService, log *logger.Logger, policy *auth.Policy) *AuthHandlers {
+	return &AuthHandlers{service, log, policy}
+}
+
+func (h *AuthHandlers) decodeCredentials(w http.ResponseWriter, r *http.Request, op string) (shared.Credentials, bool) {
+	log := h.log.With(r.Context())
+	var creds shared.Credentials
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		log.ERROR(fmt.Sprintf("%s handler: wrong content type", op))
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return creds, false
+	}
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		log.ERROR(fmt.Sprintf("%s handler: wrong JSON format: %v", op, err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return creds, false
+	}
+	return creds, true
+}
+
+func (h *AuthHandlers) Register(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	creds, ok := h.decodeCredentials(w, r, "Register")
+	if !ok {
+		return
+	}
+
+	user, err := h.service.Register(r.Context(), creds)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Register handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.ValidationError:
+			writeValidation(w, r, e)
+		case *client.StatusError:
+			shared.WriteError(w, r, http.StatusConflict, shared.CodeConflict, e.Error())
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	log.INFO(fmt.Sprintf("Register handler: user created, id=%d", user.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	creds, ok := h.decodeCredentials(w, r, "Login")
+	if !ok {
+		return
+	}
+
+	token, err := h.service.Login(r.Context(), creds)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Login handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.UnauthorizedError:
+			shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, e.Error())
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	log.INFO(fmt.Sprintf("Login handler: token issued for id=%d", token.User.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *AuthHandlers) ListUsers(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if err := h.policy.Authorize(r.Context(), auth.ActionManageUsers); err != nil {
+		writeForbidden(w, r, err)
+		return
+	}
+
+	users, err := h.service.ListUsers(r.Context())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("ListUsers handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		shared.WriteInternalError(w, r)
+		return
+	}
+
+	log.INFO(fmt.Sprintf("ListUsers handler executed successfully, count=%d", len(users)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+func (h *AuthHandlers) SetRole(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if err := h.policy.Authorize(r.Context(), auth.ActionManageUsers); err != nil {
+		writeForbidden(w, r, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.ERROR(fmt.Sprintf("SetRole handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		log.ERROR("SetRole handler: wrong content type")
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	defer r.Body.Close()
+
+	var req shared.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ERROR(fmt.Sprintf("SetRole handler: wrong JSON format: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return
+	}
+
+	user, err := h.service.SetRole(r.Context(), userID, req.Role)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("SetRole handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.ValidationError:
+			writeValidation(w, r, e)
+		case *client.NotFoundError:
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, e.Error())
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	log.INFO(fmt.Sprintf("SetRole handler: user %d is now %s", user.ID, user.Role))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"myproject/project/api-service/client"
+	"myproject/project/auth"
+	"myproject/project/shared"
+	"net/http"
+	"strings"
+)
+
+func (h *Handlers) BatchPost(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, auth.ActionWrite) {
+		return
+	}
+	var tasks []shared.Task
+	mode, ok := h.decodeBatch(w, r, "BatchPost", &tasks)
+	if !ok {
+		return
+	}
+	resp, err := h.svc(r).BatchCreate(r.Context(), tasks, mode)
+	h.writeBatch(w, r, "BatchPost", http.StatusCreated, resp, err)
+}
+
+func (h *Handlers) BatchUpdate(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, auth.ActionWrite) {
+		return
+	}
+	var patches []shared.BatchPatch
+	mode, ok := h.decodeBatch(w, r, "BatchUpdate", &patches)
+	if !ok {
+		return
+	}
+	resp, err := h.svc(r).BatchUpdate(r.Context(), patches, mode)
+	h.writeBatch(w, r, "BatchUpdate", http.StatusOK, resp, err)
+}
+
+func (h *Handlers) BatchDelete(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, auth.ActionDelete) {
+		return
+	}
+	var ids []int
+	mode, ok := h.decodeBatch(w, r, "BatchDelete", &ids)
+	if !ok {
+		return
+	}
+	resp, err := h.svc(r).BatchDelete(r.Context(), ids, mode)
+	h.writeBatch(w, r, "BatchDelete", http.StatusOK, resp, err)
+}
+
+func (h *Handlers) decodeBatch(w http.ResponseWriter, r *http.Request, op string, dst any) (string, bool) {
+	log := h.log.With(r.Context())
+	mode, err := shared.ParseBatchMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("%s handler: %v", op, err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return "", false
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		log.ERROR(fmt.Sprintf("%s handler: wrong content type", op))
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return "", false
+	}
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		log.ERROR(fmt.Sprintf("%s handler: wrong JSON format: %v", op, err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return "", false
+	}
+	return mode, true
+}
+
+func (h *Handlers) writeBatch(w http.ResponseWriter, r *http.Request, op string, success int, resp *shared.BatchResponse, err error) {
+	log := h.log.With(r.Context())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("%s handler: service error: %v", op, err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.ValidationError:
+			writeValidation(w, r, e)
+		case *client.NotFoundError:
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, e.Error())
+		case *client.ConflictError:
+			writeConflict(w, r, e)
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	status := success
+	if resp.Mode == shared.BatchPartial {
+		status = http.StatusMultiStatus
+	}
+	log.INFO(fmt.Sprintf("%s handler executed successfully, results=%d", op, len(resp.Results)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	logger "myproject/project/Logger"
 	"myproject/project/api-service/client"
 	"myproject/project/api-service/service"
+	"myproject/project/auth"
 	"myproject/project/shared"
 	"net/http"
 	"strconv"
@@ -17,74 +21,177 @@ import (
 type Handlers struct {
 	service service.Service
 	log     *logger.Logger
+	policy  *auth.Policy
 }
 
-func NewHandler(service service.Service, log *logger.Logger) *Handlers {
-	return &Handlers{service, log}
+func NewHandler(service service.Service, log *logger.Logger, policy *auth.Policy) *Handlers {
+	return &Handlers{service, log, policy}
+}
+
+// authorize спрашивает политику доступа и при отказе отвечает 403.
+func (h *Handlers) authorize(w http.ResponseWriter, r *http.Request, action auth.Action) bool {
+	err := h.policy.Authorize(r.Context(), action)
+	if err == nil {
+		return true
+	}
+	writeForbidden(w, r, err)
+	return false
+}
+
+func writeForbidden(w http.ResponseWriter, r *http.Request, err error) {
+	resp := shared.ErrorResponse{Code: shared.CodeForbidden, Message: err.Error()}
+	if fe, ok := err.(*auth.ForbiddenError); ok {
+		resp.Details = map[string]any{
+			"role":          string(fe.Role),
+			"action":        string(fe.Action),
+			"required_role": string(fe.Required),
+		}
+	}
+	shared.WriteErrorResponse(w, r, http.StatusForbidden, resp)
+}
+
+// writeConflict отвечает 409 с id задачи, с которой конфликтует запрос.
+func writeConflict(w http.ResponseWriter, r *http.Request, e *client.ConflictError) {
+	shared.WriteErrorResponse(w, r, http.StatusConflict, shared.ErrorResponse{
+		Code:    shared.CodeConflict,
+		Message: e.Msg,
+		Details: map[string]any{"task_id": e.TaskID},
+	})
+}
+
+// writeValidation передаёт пользователю ошибку валидации db-service без изменений.
+func writeValidation(w http.ResponseWriter, r *http.Request, e *client.ValidationError) {
+	shared.WriteErrorResponse(w, r, http.StatusBadRequest, shared.ErrorResponse{
+		Code:    shared.CodeValidation,
+		Message: e.Msg,
+		Details: e.Details,
+	})
+}
+
+// writeUpstreamError отвечает 503 с Retry-After, если db-service недоступен,
+// и 504, если истёк дедлайн маршрута. Если запрос отменил сам клиент,
+// отвечать уже некому - ответ не пишется.
+func writeUpstreamError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var unavailable *client.UpstreamUnavailableError
+	switch {
+	case errors.As(err, &unavailable):
+		seconds := int(math.Ceil(unavailable.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+		shared.WriteError(w, r, http.StatusServiceUnavailable, shared.CodeUpstreamUnavailable, "db-service temporarily unavailable")
+		return true
+	case errors.Is(err, context.DeadlineExceeded):
+		shared.WriteError(w, r, http.StatusGatewayTimeout, shared.CodeUpstreamTimeout, "upstream request timed out")
+		return true
+	case errors.Is(err, context.Canceled):
+		return true
+	}
+	return false
+}
+
+// svc возвращает сервис, действующий от имени аутентифицированного пользователя.
+// Запросы ограничены его задачами, если роли не разрешено ActionAllOwners.
+func (h *Handlers) svc(r *http.Request) *service.Service {
+	userID, _ := shared.UserIDFromContext(r.Context())
+	svc := h.service.ForUser(userID)
+	if claims, _ := auth.ClaimsFromContext(r.Context()); claims.Role.Can(auth.ActionAllOwners) {
+		svc = svc.AllOwners()
+	}
+	return svc
 }
 
 func (h *Handlers) Get(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionRead) {
+		return
+	}
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
 	taskID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Get handler: invalid id: %v", err))
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Get handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
 		return
 	}
-	h.log.DEBUG(fmt.Sprintf("Get handler: received id=%d", taskID))
+	log.DEBUG(fmt.Sprintf("Get handler: received id=%d", taskID))
 
-	task, err := h.service.Get(taskID)
+	task, err := h.svc(r).Get(r.Context(), taskID)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Get handler: service error: %v", err))
+		log.ERROR(fmt.Sprintf("Get handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
 		switch err := err.(type) {
 		case *client.NotFoundError:
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case *client.StatusError:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, err.Error())
 		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
 
-	h.log.INFO(fmt.Sprintf("Get handler: task retrieved successfully, id=%d", taskID))
+	log.INFO(fmt.Sprintf("Get handler: task retrieved successfully, id=%d", taskID))
+	etag := shared.ETag(task.Version)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
 }
 
 func (h *Handlers) Post(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionWrite) {
+		return
+	}
 	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-		h.log.ERROR("Post handler: wrong content type")
-		http.Error(w, "должен быть JSON", http.StatusUnsupportedMediaType)
+		log.ERROR("Post handler: wrong content type")
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
 		return
 	}
 	defer r.Body.Close()
 
+	key := r.Header.Get(shared.IdempotencyKeyHeader)
+	if len(key) > shared.MaxIdempotencyKeyLen {
+		log.ERROR("Post handler: idempotency key too long")
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, fmt.Sprintf("Idempotency-Key must be at most %d characters", shared.MaxIdempotencyKeyLen))
+		return
+	}
+
 	var task shared.Task
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		h.log.ERROR(fmt.Sprintf("Post handler: wrong JSON format: %v", err))
-		http.Error(w, "неверный формат JSON", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Post handler: wrong JSON format: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
 		return
 	}
 
-	h.log.DEBUG(fmt.Sprintf("Post handler: received task %+v", task))
-	ID, err := h.service.Post(task)
+	log.DEBUG("Post handler: received task", "body_bytes", r.ContentLength)
+	ID, err := h.svc(r).Post(r.Context(), task, key)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Post handler: service error: %v", err))
+		log.ERROR(fmt.Sprintf("Post handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
 		switch e := err.(type) {
+		case *client.ConflictError:
+			writeConflict(w, r, e)
 		case *client.ContentTypeError:
-			http.Error(w, e.Error(), http.StatusUnsupportedMediaType)
+			shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, e.Error())
+		case *client.IdempotencyKeyReusedError:
+			shared.WriteError(w, r, http.StatusUnprocessableEntity, shared.CodeIdempotencyKeyReused, e.Error())
+		case *client.ValidationError:
+			writeValidation(w, r, e)
 		case *client.StatusError:
-			http.Error(w, e.Error(), http.StatusConflict)
+			shared.WriteError(w, r, http.StatusConflict, shared.CodeConflict, e.Error())
 		default:
-			http.Error(w, e.Error(), http.StatusInternalServerError)
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
 
-	h.log.INFO(fmt.Sprintf("Post handler: task created successfully, ID=%d", ID))
+	log.INFO(fmt.Sprintf("Post handler: task created successfully, ID=%d", ID))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -94,49 +201,112 @@ func (h *Handlers) Post(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) GetAll(w http.ResponseWriter, r *http.Request) {
-	h.log.DEBUG("GetAll handler: called")
-	tasks, err := h.service.GetAll()
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionRead) {
+		return
+	}
+	log.DEBUG("GetAll handler: called")
+	filter, err := shared.ParseTaskFilter(r.URL.Query())
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("GetAll handler: service error: %v", err))
+		log.ERROR(fmt.Sprintf("GetAll handler: invalid query: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return
+	}
+
+	page, err := h.svc(r).GetAll(r.Context(), filter)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("GetAll handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
 		switch e := err.(type) {
-		case *client.StatusError:
-			http.Error(w, e.Error(), http.StatusInternalServerError)
+		case *client.ValidationError:
+			writeValidation(w, r, e)
 		default:
-			http.Error(w, "неверный формат JSON", http.StatusBadRequest)
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
-	h.log.INFO(fmt.Sprintf("GetAll handler executed successfully, tasks_count=%d", len(tasks)))
+	log.INFO(fmt.Sprintf("GetAll handler executed successfully, tasks_count=%d", len(page.Tasks)))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tasks)
+	json.NewEncoder(w).Encode(page)
+}
+
+func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionRead) {
+		return
+	}
+	q, err := shared.ParseSearchQuery(r.URL.Query())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Search handler: invalid query: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return
+	}
+	log.DEBUG("Search handler: received query", "query_len", len(q.Q))
+
+	result, err := h.svc(r).Search(r.Context(), q)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Search handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.ValidationError:
+			writeValidation(w, r, e)
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+	log.INFO(fmt.Sprintf("Search handler executed successfully, results=%d", len(result.Results)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
 
 func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	taskID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Delete handler: invalid id: %v", err))
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Delete handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
 		return
 	}
-	h.log.DEBUG(fmt.Sprintf("Delete handler: received id=%d", taskID))
+	purge := r.URL.Query().Get("purge") == "true"
+	log.DEBUG(fmt.Sprintf("Delete handler: received id=%d, purge=%t", taskID, purge))
 
-	err = h.service.Delete(taskID)
+	action := auth.ActionDelete
+	if purge {
+		action = auth.ActionPurge
+	}
+	if !h.authorize(w, r, action) {
+		return
+	}
+
+	if purge {
+		err = h.svc(r).Purge(r.Context(), taskID)
+	} else {
+		err = h.svc(r).Delete(r.Context(), taskID)
+	}
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Delete handler: service error: %v", err))
+		log.ERROR(fmt.Sprintf("Delete handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
 		switch e := err.(type) {
 		case *client.NotFoundError:
-			http.Error(w, e.Error(), http.StatusNotFound)
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, e.Error())
 		case *client.StatusError:
-			http.Error(w, e.Error(), http.StatusConflict)
+			shared.WriteError(w, r, http.StatusConflict, shared.CodeConflict, e.Error())
 		default:
-			http.Error(w, e.Error(), http.StatusInternalServerError)
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
 
-	h.log.INFO(fmt.Sprintf("Delete handler executed successfully, id=%d", taskID))
+	log.INFO(fmt.Sprintf("Delete handler executed successfully, id=%d", taskID))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(shared.DeleteOrUpdateResponse{
@@ -146,35 +316,203 @@ func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) Update(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionWrite) {
+		return
+	}
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	taskID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Update handler: invalid id: %v", err))
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Update handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+	log.DEBUG(fmt.Sprintf("Update handler: received id=%d", taskID))
+
+	ct := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "application/json") && !strings.HasPrefix(ct, "application/merge-patch+json") {
+		log.ERROR("Update handler: wrong content type")
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
 		return
 	}
-	h.log.DEBUG(fmt.Sprintf("Update handler: received id=%d", taskID))
+	defer r.Body.Close()
 
-	err = h.service.Update(taskID)
+	var patch shared.TaskPatch
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patch); err != nil {
+		log.ERROR(fmt.Sprintf("Update handler: wrong JSON format: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return
+	}
+
+	task, err := h.svc(r).Update(r.Context(), taskID, patch)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Update handler: service error: %v", err))
+		log.ERROR(fmt.Sprintf("Update handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
 		switch e := err.(type) {
+		case *client.ConflictError:
+			writeConflict(w, r, e)
 		case *client.NotFoundError:
-			http.Error(w, e.Error(), http.StatusNotFound)
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, e.Error())
+		case *client.ValidationError:
+			writeValidation(w, r, e)
 		case *client.StatusError:
-			http.Error(w, e.Error(), http.StatusConflict)
+			shared.WriteError(w, r, http.StatusConflict, shared.CodeConflict, e.Error())
 		default:
-			http.Error(w, e.Error(), http.StatusInternalServerError)
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
 
-	h.log.INFO(fmt.Sprintf("Update handler executed successfully, id=%d", taskID))
+	log.INFO(fmt.Sprintf("Update handler executed successfully, id=%d", taskID))
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", shared.ETag(task.Version))
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(shared.DeleteOrUpdateResponse{
-		Message:    "Задача успешно выполнена",
-		StatusCode: http.StatusOK,
-	})
+	json.NewEncoder(w).Encode(task)
+}
+
+func (h *Handlers) Replace(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionWrite) {
+		return
+	}
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	taskID, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Replace handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+	log.DEBUG(fmt.Sprintf("Replace handler: received id=%d", taskID))
+
+	ifMatch := r.Header.Get("If-Match")
+	if _, err := shared.ParseETag(ifMatch); err != nil {
+		log.ERROR(fmt.Sprintf("Replace handler: %v", err))
+		msg := "invalid If-Match header"
+		if errors.Is(err, shared.ErrWeakETag) {
+			msg = "If-Match requires a strong ETag"
+		}
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, msg)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		log.ERROR("Replace handler: wrong content type")
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	defer r.Body.Close()
+
+	var task shared.Task
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		log.ERROR(fmt.Sprintf("Replace handler: wrong JSON format: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return
+	}
+
+	updated, err := h.svc(r).Replace(r.Context(), taskID, task, ifMatch)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Replace handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.ConflictError:
+			writeConflict(w, r, e)
+		case *client.NotFoundError:
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, e.Error())
+		case *client.PreconditionFailedError:
+			if e.ETag != "" {
+				w.Header().Set("ETag", e.ETag)
+			}
+			shared.WriteError(w, r, http.StatusPreconditionFailed, shared.CodePreconditionFailed, e.Error())
+		case *client.ValidationError:
+			writeValidation(w, r, e)
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	log.INFO(fmt.Sprintf("Replace handler executed successfully, id=%d", taskID))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", shared.ETag(updated.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handlers) Trash(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionRead) {
+		return
+	}
+	log.DEBUG("Trash handler: called")
+	filter, err := shared.ParseTaskFilter(r.URL.Query())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Trash handler: invalid query: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return
+	}
+
+	page, err := h.svc(r).Trash(r.Context(), filter)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Trash handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.ValidationError:
+			writeValidation(w, r, e)
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+	log.INFO(fmt.Sprintf("Trash handler executed successfully, tasks_count=%d", len(page.Tasks)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func (h *Handlers) Restore(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	if !h.authorize(w, r, auth.ActionWrite) {
+		return
+	}
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	taskID, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Restore handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+	log.DEBUG(fmt.Sprintf("Restore handler: received id=%d", taskID))
+
+	task, err := h.svc(r).Restore(r.Context(), taskID)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Restore handler: service error: %v", err))
+		if writeUpstreamError(w, r, err) {
+			return
+		}
+		switch e := err.(type) {
+		case *client.ConflictError:
+			writeConflict(w, r, e)
+		case *client.NotFoundError:
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, e.Error())
+		default:
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	log.INFO(fmt.Sprintf("Restore handler executed successfully, id=%d", taskID))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", shared.ETag(task.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(task)
 }
@@ -1,42 +1,151 @@
 package main
 
 import (
-	logger "myproject/project/Logger"
+	loggerpkg "myproject/project/Logger"
 	"myproject/project/api-service/client"
 	"myproject/project/api-service/handlers"
 	"myproject/project/api-service/service"
+	"myproject/project/auth"
+	"myproject/project/config"
+	"myproject/project/health"
+	"myproject/project/httpserver"
+	"myproject/project/metrics"
 	"myproject/project/middleware"
 	"myproject/project/shared"
+	"myproject/project/tracing"
 
+	"context"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
-
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/mux"
-	"gopkg.in/yaml.v3"
 )
 
 func main() {
-	logger := logger.NewLogger()
-	cfg := shared.Config{}
-	data, _ := os.ReadFile("config.yaml")
-	yaml.Unmarshal(data, &cfg)
-	client := client.NewClient(cfg.DBService.URL, *logger)
+	if err := run(); err != nil {
+		log.Fatalf("api-service: %v", err)
+	}
+}
+
+// run возвращает ошибку вместо log.Fatalf, чтобы отложенные закрытия
+// трассировки и логов выполнились и при сбое.
+func run() (err error) {
+	// SIGINT/SIGTERM запускают остановку, повторный сигнал завершает процесс сразу
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	path := flag.String("config", "config.yaml", "path to the YAML config")
+	flag.Parse()
+	cfg := config.DefaultAPI()
+	if err := config.Load(*path, cfg); err != nil {
+		return err
+	}
+	logger, err := loggerpkg.New(cfg.Log, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	// Закрывается последним, после трассировки
+	defer func() {
+		if err != nil {
+			logger.ERROR(err.Error())
+		}
+		logger.Close()
+	}()
+	logger.RotateOnSIGHUP(ctx)
+
+	shutdownTracing, err := tracing.Setup(ctx, "api-service", cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+	issuer, err := auth.NewIssuer(cfg.Auth.Secret, cfg.Auth.TokenTTL)
+	if err != nil {
+		return fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	policy := auth.NewPolicy(logger)
+
+	reg := metrics.NewRegistry()
+	opts := client.Options{
+		Retry:   cfg.DBService.Retry,
+		Breaker: cfg.DBService.Breaker,
+		Metrics: metrics.NewUpstream(reg, "db-service"),
+	}
+	opts.Signer, err = auth.NewSigner(cfg.DBService.Secret)
+	if err != nil {
+		return fmt.Errorf("invalid db_service config: %w", err)
+	}
+	if tlsCfg := cfg.DBService.TLS; tlsCfg.CertFile != "" {
+		opts.TLS, err = auth.ClientTLSConfig(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("invalid db_service tls config: %w", err)
+		}
+	}
+
+	client := client.NewClient(cfg.DBService.URL, *logger, opts)
+
+	// SIGHUP или изменение файла применяют уровень логов, дедлайны и настройки
+	// клиента db-service; остальное требует перезапуска
+	reloader := config.NewReloader(*path, cfg, config.DefaultAPI, logger)
+	reloader.OnChange(func(cfg *config.API) {
+		if err := logger.SetLevel(cfg.Log.Level); err != nil {
+			logger.ERROR(fmt.Sprintf("config reload: %v", err))
+		}
+		client.Reconfigure(cfg.DBService.URL, cfg.DBService.Retry, cfg.DBService.Breaker)
+	})
+	reloader.Watch(ctx, config.WatchInterval)
+
+	authService := service.NewAuthService(client, issuer, cfg.Auth.RoleCacheTTL, logger)
+	authHandler := handlers.NewAuthHandler(authService, logger, policy)
 	service := service.NewService(client, logger)
-	handler := handlers.NewHandler(*service, logger)
+	handler := handlers.NewHandler(*service, logger, policy)
+	adminHandler := handlers.NewAdminHandler(reloader.Handler(), logger, policy)
 
 	r := mux.NewRouter()
-	r.Use(middleware.LoggingMiddlware)
+	r.NotFoundHandler = shared.NotFoundHandler()
+	r.MethodNotAllowedHandler = shared.MethodNotAllowedHandler()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Tracing)
+	r.Use(middleware.AccessLog(logger.Access))
+	r.Use(middleware.Metrics(metrics.NewHTTP(reg, "api-service")))
+	r.Use(middleware.DeadlineFrom(func() shared.Timeouts { return reloader.Current().Timeouts }))
 
-	r.HandleFunc("/tasks", handler.Post).Methods("POST")
-	r.HandleFunc("/tasks/{id}", handler.Get).Methods("GET")
-	r.HandleFunc("/tasks", handler.GetAll).Methods("GET")
-	r.HandleFunc("/tasks/{id}", handler.Update).Methods("PATCH")
-	r.HandleFunc("/tasks/{id}", handler.Delete).Methods("DELETE")
+	r.Handle("/metrics", metrics.Handler(reg)).Methods("GET")
+	r.Handle("/healthz", health.Liveness()).Methods("GET")
+	ready := health.New(health.DefaultTimeout).Add("db-service", client.Ping)
+	r.Handle("/readyz", ready.Readiness()).Methods("GET")
+	r.HandleFunc("/auth/register", authHandler.Register).Methods("POST")
+	r.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
 
-	log.Println("Server started at :8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatalf("server failed: %v", err)
-	}
+	// Остальные маршруты доступны только с валидным токеном
+	tasks := r.NewRoute().Subrouter()
+	tasks.Use(middleware.Authenticate(issuer))
+	tasks.Use(middleware.CurrentRole(authService.Roles()))
+	tasks.HandleFunc("/users", authHandler.ListUsers).Methods("GET")
+	tasks.HandleFunc("/users/{id}/role", authHandler.SetRole).Methods("PUT")
+	tasks.HandleFunc("/admin/config", adminHandler.Config).Methods("GET")
+	tasks.HandleFunc("/tasks/batch", handler.BatchPost).Methods("POST")
+	tasks.HandleFunc("/tasks/batch", handler.BatchUpdate).Methods("PATCH")
+	tasks.HandleFunc("/tasks/batch", handler.BatchDelete).Methods("DELETE")
+	tasks.HandleFunc("/tasks/search", handler.Search).Methods("GET")
+	tasks.HandleFunc("/tasks/trash", handler.Trash).Methods("GET")
+	tasks.HandleFunc("/tasks/{id}/restore", handler.Restore).Methods("POST")
+	tasks.HandleFunc("/tasks", handler.Post).Methods("POST")
+	tasks.HandleFunc("/tasks/{id}", handler.Get).Methods("GET")
+	tasks.HandleFunc("/tasks", handler.GetAll).Methods("GET")
+	tasks.HandleFunc("/tasks/{id}", handler.Update).Methods("PATCH")
+	tasks.HandleFunc("/tasks/{id}", handler.Replace).Methods("PUT")
+	tasks.HandleFunc("/tasks/{id}", handler.Delete).Methods("DELETE")
+
+	srv := httpserver.New(cfg.Listen.Addr, r, cfg.Server)
+	logger.INFO("Server started at " + cfg.Listen.Addr)
+	return httpserver.Serve(ctx, srv, cfg.Server, logger, srv.ListenAndServe, ready.Drain)
 }
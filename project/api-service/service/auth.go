@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	logger "myproject/project/Logger"
+	"myproject/project/api-service/client"
+	"myproject/project/auth"
+	"myproject/project/shared"
+	"time"
+)
+
+type AuthService struct {
+	client *client.Client
+	issuer *auth.Issuer
+	roles  *auth.RoleCache
+	log    *logger.Logger
+}
+
+// NewAuthService создаёт сервис. Роли пользователей кешируются на roleTTL.
+func NewAuthService(c *client.Client, issuer *auth.Issuer, roleTTL time.Duration, log *logger.Logger) *AuthService {
+	s := &AuthService{client: c, issuer: issuer, log: log}
+	s.roles = auth.NewRoleCache(roleTTL, s.lookupRole)
+	return s
+}
+
+// Roles возвращает кеш текущих ролей для middleware.CurrentRole.
+func (s *AuthService) Roles() *auth.RoleCache {
+	return s.roles
+}
+
+func (s *AuthService) lookupRole(ctx context.Context, userID int) (auth.Role, error) {
+	user, err := s.client.GetUser(ctx, userID)
+	if err != nil {
+		var nf *client.NotFoundError
+		if errors.As(err, &nf) {
+			return "", auth.ErrUnknownUser
+		}
+		s.log.With(ctx).ERROR(fmt.Sprintf("Service: role lookup for id=%d failed: %v", userID, err))
+		return "", err
+	}
+	return auth.Role(user.Role), nil
+}
+
+func (s *AuthService) Register(ctx context.Context, creds shared.Credentials) (*shared.User, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Register username=%s", creds.Username))
+	user, err := s.client.Register(ctx, creds)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Register failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: Register executed successfully, id=%d", user.ID))
+	return user, nil
+}
+
+func (s *AuthService) Login(ctx context.Context, creds shared.Credentials) (*shared.TokenResponse, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Login username=%s", creds.Username))
+	user, err := s.client.Authenticate(ctx, creds)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Login failed: %v", err))
+		return nil, err
+	}
+	token, exp, err := s.issuer.Issue(user.ID, user.Username, auth.Role(user.Role))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Login token issue failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: Login executed successfully, id=%d", user.ID))
+	return &shared.TokenResponse{Token: token, ExpiresAt: exp, User: *user}, nil
+}
+
+func (s *AuthService) ListUsers(ctx context.Context) ([]shared.User, error) {
+	log := s.log.With(ctx)
+	log.DEBUG("Service: ListUsers")
+	users, err := s.client.ListUsers(ctx)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: ListUsers failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: ListUsers executed successfully, count=%d", len(users)))
+	return users, nil
+}
+
+func (s *AuthService) SetRole(ctx context.Context, userID int, role string) (*shared.User, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: SetRole id=%d role=%s", userID, role))
+	user, err := s.client.SetUserRole(ctx, userID, role)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: SetRole failed: %v", err))
+		return nil, err
+	}
+	s.roles.Forget(user.ID)
+	log.INFO(fmt.Sprintf("Service: SetRole executed successfully, id=%d role=%s", user.ID, user.Role))
+	return user, nil
+}
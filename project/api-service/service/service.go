@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	logger "myproject/project/Logger"
 	"myproject/project/api-service/client"
@@ -16,57 +17,168 @@ func NewService(c *client.Client, log *logger.Logger) *Service {
 	return &Service{client: c, log: log}
 }
 
-func (s *Service) Get(id int) (*shared.Task, error) {
-	s.log.DEBUG(fmt.Sprintf("Service: Get task id=%d", id))
-	task, err := s.client.GetTask(id)
+// ForUser возвращает копию сервиса, обращающуюся к db-service от имени пользователя.
+func (s *Service) ForUser(userID int) *Service {
+	return &Service{client: s.client.As(userID), log: s.log}
+}
+
+// AllOwners возвращает копию сервиса, которой db-service не ограничивает задачи владельцем.
+func (s *Service) AllOwners() *Service {
+	return &Service{client: s.client.AllOwners(), log: s.log}
+}
+
+func (s *Service) Get(ctx context.Context, id int) (*shared.Task, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Get task id=%d", id))
+	task, err := s.client.GetTask(ctx, id)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("Service: Get task failed: %v", err))
+		log.ERROR(fmt.Sprintf("Service: Get task failed: %v", err))
 		return nil, err
 	}
-	s.log.INFO(fmt.Sprintf("Service: Get task executed successfully, id=%d", id))
+	log.INFO(fmt.Sprintf("Service: Get task executed successfully, id=%d", id))
 	return task, nil
 }
 
-func (s *Service) GetAll() ([]shared.Task, error) {
-	s.log.DEBUG("Service: GetAll tasks")
-	tasks, err := s.client.GetAllTasks()
+func (s *Service) GetAll(ctx context.Context, filter shared.TaskFilter) (*shared.TaskPage, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: GetAll tasks, filter=%+v", filter))
+	page, err := s.client.GetAllTasks(ctx, filter)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("Service: GetAll tasks failed: %v", err))
+		log.ERROR(fmt.Sprintf("Service: GetAll tasks failed: %v", err))
 		return nil, err
 	}
-	s.log.INFO(fmt.Sprintf("Service: GetAll executed successfully, tasks_count=%d", len(tasks)))
-	return tasks, nil
+	log.INFO(fmt.Sprintf("Service: GetAll executed successfully, tasks_count=%d, total=%d", len(page.Tasks), page.Total))
+	return page, nil
 }
 
-func (s *Service) Post(task shared.Task) (int64, error) {
-	s.log.DEBUG(fmt.Sprintf("Service: Post task %+v", task))
-	ID, err := s.client.PostTask(task)
+func (s *Service) Post(ctx context.Context, task shared.Task, idempotencyKey string) (int64, error) {
+	log := s.log.With(ctx)
+	log.DEBUG("Service: Post task", "idempotency_key", idempotencyKey)
+	ID, err := s.client.PostTask(ctx, task, idempotencyKey)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("Service: Post task failed: %v", err))
+		log.ERROR(fmt.Sprintf("Service: Post task failed: %v", err))
 		return 0, err
 	}
-	s.log.INFO(fmt.Sprintf("Service: Post task executed successfully, ID=%d", ID))
+	log.INFO(fmt.Sprintf("Service: Post task executed successfully, ID=%d", ID))
 	return ID, nil
 }
 
-func (s *Service) Delete(id int) error {
-	s.log.DEBUG(fmt.Sprintf("Service: Delete task id=%d", id))
-	err := s.client.Delete(id)
+func (s *Service) Delete(ctx context.Context, id int) error {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Delete task id=%d", id))
+	err := s.client.Delete(ctx, id)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("Service: Delete task failed: %v", err))
+		log.ERROR(fmt.Sprintf("Service: Delete task failed: %v", err))
 		return err
 	}
-	s.log.INFO(fmt.Sprintf("Service: Delete task executed successfully, id=%d", id))
+	log.INFO(fmt.Sprintf("Service: Delete task executed successfully, id=%d", id))
 	return nil
 }
 
-func (s *Service) Update(id int) error {
-	s.log.DEBUG(fmt.Sprintf("Service: Update task id=%d", id))
-	err := s.client.Update(id)
+func (s *Service) Update(ctx context.Context, id int, patch shared.TaskPatch) (*shared.Task, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Update task id=%d", id))
+	task, err := s.client.Update(ctx, id, patch)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Update task failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: Update task executed successfully, id=%d", id))
+	return task, nil
+}
+
+func (s *Service) Replace(ctx context.Context, id int, task shared.Task, ifMatch string) (*shared.Task, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Replace task id=%d, If-Match=%s", id, ifMatch))
+	updated, err := s.client.ReplaceTask(ctx, id, task, ifMatch)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Replace task failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: Replace task executed successfully, id=%d", id))
+	return updated, nil
+}
+
+func (s *Service) Trash(ctx context.Context, filter shared.TaskFilter) (*shared.TaskPage, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Trash, filter=%+v", filter))
+	page, err := s.client.GetTrash(ctx, filter)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Trash failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: Trash executed successfully, tasks_count=%d, total=%d", len(page.Tasks), page.Total))
+	return page, nil
+}
+
+func (s *Service) Restore(ctx context.Context, id int) (*shared.Task, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Restore task id=%d", id))
+	task, err := s.client.Restore(ctx, id)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Restore task failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: Restore task executed successfully, id=%d", id))
+	return task, nil
+}
+
+func (s *Service) Purge(ctx context.Context, id int) error {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: Purge task id=%d", id))
+	err := s.client.Purge(ctx, id)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("Service: Update task failed: %v", err))
+		log.ERROR(fmt.Sprintf("Service: Purge task failed: %v", err))
 		return err
 	}
-	s.log.INFO(fmt.Sprintf("Service: Update task executed successfully, id=%d", id))
+	log.INFO(fmt.Sprintf("Service: Purge task executed successfully, id=%d", id))
 	return nil
 }
+
+func (s *Service) BatchCreate(ctx context.Context, tasks []shared.Task, mode string) (*shared.BatchResponse, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: BatchCreate %d task(s), mode=%s", len(tasks), mode))
+	resp, err := s.client.BatchCreate(ctx, tasks, mode)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: BatchCreate failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: BatchCreate executed successfully, results=%d", len(resp.Results)))
+	return resp, nil
+}
+
+func (s *Service) BatchUpdate(ctx context.Context, patches []shared.BatchPatch, mode string) (*shared.BatchResponse, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: BatchUpdate %d task(s), mode=%s", len(patches), mode))
+	resp, err := s.client.BatchUpdate(ctx, patches, mode)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: BatchUpdate failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: BatchUpdate executed successfully, results=%d", len(resp.Results)))
+	return resp, nil
+}
+
+func (s *Service) BatchDelete(ctx context.Context, ids []int, mode string) (*shared.BatchResponse, error) {
+	log := s.log.With(ctx)
+	log.DEBUG(fmt.Sprintf("Service: BatchDelete %d task(s), mode=%s", len(ids), mode))
+	resp, err := s.client.BatchDelete(ctx, ids, mode)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: BatchDelete failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: BatchDelete executed successfully, results=%d", len(resp.Results)))
+	return resp, nil
+}
+
+func (s *Service) Search(ctx context.Context, q shared.SearchQuery) (*shared.SearchResponse, error) {
+	log := s.log.With(ctx)
+	log.DEBUG("Service: Search", "query_len", len(q.Q), "limit", q.Limit, "offset", q.Offset)
+	result, err := s.client.Search(ctx, q)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Service: Search failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("Service: Search executed successfully, results=%d", len(result.Results)))
+	return result, nil
+}
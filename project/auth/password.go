@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	// bcrypt учитывает только первые 72 байта пароля
+	MaxPasswordLength = 72
+)
+
+var ErrWrongPassword = errors.New("wrong password")
+
+var usernameRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,64}$`)
+
+// dummyHash сравнивается с паролем, когда пользователь не найден,
+// чтобы время ответа не выдавало существование логина.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+func ValidateCredentials(username, password string) error {
+	if !usernameRe.MatchString(username) {
+		return fmt.Errorf("username must be 3-64 characters: letters, digits, '_', '.', '-'")
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return fmt.Errorf("password must be %d-%d bytes long", MinPasswordLength, MaxPasswordLength)
+	}
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сверяет пароль с хешем. Пустой hash означает
+// отсутствующего пользователя: сравнение всё равно выполняется.
+func CheckPassword(hash, password string) error {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return ErrWrongPassword
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+	return nil
+}
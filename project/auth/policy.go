@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	logger "myproject/project/Logger"
+)
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleViewer || r == RoleEditor || r == RoleAdmin
+}
+
+type Action string
+
+const (
+	ActionRead        Action = "read"
+	ActionWrite       Action = "write"
+	ActionDelete      Action = "delete"
+	ActionPurge       Action = "purge"
+	ActionManageUsers Action = "manage_users"
+	ActionViewConfig  Action = "view_config"
+	// ActionAllOwners снимает ограничение задач владельцем
+	ActionAllOwners Action = "all_owners"
+)
+
+// requiredRole - минимальная роль для действия. Роли упорядочены:
+// viewer < editor < admin.
+//
+// Роль ограничивает действия поверх владения: db-service выполняет любой
+// запрос к задачам только в пределах задач пользователя. viewer читает свои
+// задачи, editor их создаёт и меняет, admin удаляет, очищает корзину и,
+// через ActionAllOwners, работает с задачами всех владельцев.
+var requiredRole = map[Action]Role{
+	ActionRead:        RoleViewer,
+	ActionWrite:       RoleEditor,
+	ActionDelete:      RoleAdmin,
+	ActionPurge:       RoleAdmin,
+	ActionManageUsers: RoleAdmin,
+	ActionViewConfig:  RoleAdmin,
+	ActionAllOwners:   RoleAdmin,
+}
+
+var roleRank = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Can сообщает, достаточно ли роли r для действия.
+func (r Role) Can(action Action) bool {
+	required, ok := requiredRole[action]
+	return ok && roleRank[r] >= roleRank[required]
+}
+
+// ForbiddenError возвращается, когда роли пользователя недостаточно для действия.
+type ForbiddenError struct {
+	UserID   int
+	Role     Role
+	Action   Action
+	Required Role
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("role %q is not allowed to %s (requires %q)", e.Role, e.Action, e.Required)
+}
+
+// Policy принимает решения о доступе и журналирует каждое из них.
+type Policy struct {
+	log *logger.Logger
+}
+
+func NewPolicy(log *logger.Logger) *Policy {
+	return &Policy{log: log}
+}
+
+func (p *Policy) Authorize(ctx context.Context, action Action) error {
+	log := p.log.With(ctx)
+	claims, _ := ClaimsFromContext(ctx)
+	required, ok := requiredRole[action]
+	if !ok {
+		log.ERROR(fmt.Sprintf("policy: user=%d role=%s action=%s decision=deny reason=unknown_action", claims.UserID, claims.Role, action))
+		return &ForbiddenError{UserID: claims.UserID, Role: claims.Role, Action: action, Required: RoleAdmin}
+	}
+	if !claims.Role.Can(action) {
+		log.INFO(fmt.Sprintf("policy: user=%d role=%s action=%s decision=deny required=%s", claims.UserID, claims.Role, action, required))
+		return &ForbiddenError{UserID: claims.UserID, Role: claims.Role, Action: action, Required: required}
+	}
+	log.INFO(fmt.Sprintf("policy: user=%d role=%s action=%s decision=allow", claims.UserID, claims.Role, action))
+	return nil
+}
+
+type claimsKey struct{}
+
+func ContextWithClaims(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(Claims)
+	return c, ok
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	logger "myproject/project/Logger"
+	"myproject/project/shared"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	l, err := logger.New(shared.LogConfig{}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	tests := []struct {
+		role   Role
+		action Action
+		allow  bool
+	}{
+		{RoleViewer, ActionRead, true},
+		{RoleViewer, ActionWrite, false},
+		{RoleViewer, ActionDelete, false},
+		{RoleEditor, ActionRead, true},
+		{RoleEditor, ActionWrite, true},
+		{RoleEditor, ActionDelete, false},
+		{RoleEditor, ActionPurge, false},
+		{RoleEditor, ActionManageUsers, false},
+		{RoleAdmin, ActionDelete, true},
+		{RoleAdmin, ActionPurge, true},
+		{RoleAdmin, ActionManageUsers, true},
+		{RoleAdmin, ActionViewConfig, true},
+		{RoleViewer, ActionAllOwners, false},
+		{RoleEditor, ActionAllOwners, false},
+		{RoleAdmin, ActionAllOwners, true},
+		{RoleAdmin, Action("unknown"), false},
+		{Role(""), ActionRead, false},
+		{Role("root"), ActionRead, false},
+	}
+	p := NewPolicy(testLogger(t))
+	for _, tt := range tests {
+		ctx := ContextWithClaims(context.Background(), Claims{UserID: 1, Role: tt.role})
+		err := p.Authorize(ctx, tt.action)
+		if tt.allow {
+			if err != nil {
+				t.Errorf("%s/%s: unexpected deny: %v", tt.role, tt.action, err)
+			}
+			continue
+		}
+		var fe *ForbiddenError
+		if !errors.As(err, &fe) {
+			t.Errorf("%s/%s: err = %v, want *ForbiddenError", tt.role, tt.action, err)
+			continue
+		}
+		if fe.UserID != 1 || fe.Role != tt.role || fe.Action != tt.action {
+			t.Errorf("%s/%s: ForbiddenError = %+v", tt.role, tt.action, fe)
+		}
+	}
+}
+
+func TestPolicyAuthorizeWithoutClaims(t *testing.T) {
+	p := NewPolicy(testLogger(t))
+	var fe *ForbiddenError
+	if err := p.Authorize(context.Background(), ActionRead); !errors.As(err, &fe) {
+		t.Fatalf("err = %v, want *ForbiddenError", err)
+	}
+}
+
+func TestRoleCache(t *testing.T) {
+	var calls atomic.Int32
+	role := RoleAdmin
+	c := NewRoleCache(time.Hour, func(ctx context.Context, userID int) (Role, error) {
+		calls.Add(1)
+		return role, nil
+	})
+	ctx := context.Background()
+
+	for range 3 {
+		if got, err := c.Role(ctx, 1); err != nil || got != RoleAdmin {
+			t.Fatalf("Role = %q, %v", got, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("lookup calls = %d, want 1", n)
+	}
+
+	role = RoleViewer
+	c.Forget(1)
+	if got, _ := c.Role(ctx, 1); got != RoleViewer {
+		t.Fatalf("after Forget role = %q, want viewer", got)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("lookup calls = %d, want 2", n)
+	}
+}
+
+func TestRoleCacheNoTTL(t *testing.T) {
+	var calls atomic.Int32
+	c := NewRoleCache(0, func(ctx context.Context, userID int) (Role, error) {
+		calls.Add(1)
+		return RoleEditor, nil
+	})
+	c.Role(context.Background(), 1)
+	c.Role(context.Background(), 1)
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("lookup calls = %d, want 2", n)
+	}
+}
+
+func TestRoleCacheErrorsNotCached(t *testing.T) {
+	fail := errors.New("db-service down")
+	err := fail
+	c := NewRoleCache(time.Hour, func(ctx context.Context, userID int) (Role, error) {
+		return RoleEditor, err
+	})
+	if _, got := c.Role(context.Background(), 1); !errors.Is(got, fail) {
+		t.Fatalf("err = %v, want %v", got, fail)
+	}
+	err = nil
+	if got, e := c.Role(context.Background(), 1); e != nil || got != RoleEditor {
+		t.Fatalf("Role = %q, %v", got, e)
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnknownUser возвращает функция поиска роли, если пользователя больше нет.
+var ErrUnknownUser = errors.New("user no longer exists")
+
+// RoleLookup возвращает текущую роль пользователя из хранилища.
+type RoleLookup func(ctx context.Context, userID int) (Role, error)
+
+// RoleCache хранит роли пользователей не дольше ttl. Роль в JWT действует
+// до истечения токена, поэтому права проверяются по роли из кеша:
+// понижение роли вступает в силу не позже чем через ttl.
+type RoleCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	lookup  RoleLookup
+	entries map[int]cachedRole
+}
+
+type cachedRole struct {
+	role    Role
+	expires time.Time
+}
+
+// NewRoleCache создаёт кеш. ttl = 0 отключает кеширование: роль
+// запрашивается на каждый запрос.
+func NewRoleCache(ttl time.Duration, lookup RoleLookup) *RoleCache {
+	return &RoleCache{ttl: ttl, lookup: lookup, entries: map[int]cachedRole{}}
+}
+
+func (c *RoleCache) Role(ctx context.Context, userID int) (Role, error) {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.role, nil
+	}
+
+	role, err := c.lookup(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUnknownUser) {
+			c.Forget(userID)
+		}
+		return "", err
+	}
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.entries[userID] = cachedRole{role: role, expires: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return role, nil
+}
+
+// Forget сбрасывает роль пользователя, например после её смены.
+func (c *RoleCache) Forget(userID int) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"myproject/project/shared"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Signature"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+
+	signatureVersion = "v1="
+)
+
+var (
+	ErrSignatureMissing = errors.New("request signature missing")
+	ErrSignatureInvalid = errors.New("request signature invalid")
+	ErrSignatureExpired = errors.New("request signature outside allowed time window")
+	ErrSignatureReplay  = errors.New("request signature already used")
+)
+
+// Signer подписывает запросы api-service к db-service общим секретом.
+// В подпись входят метод, путь с query, время, nonce, X-User-ID, X-Owner-Scope,
+// If-Match, Idempotency-Key, Content-Type и SHA-256 тела.
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret string) (*Signer, error) {
+	if len(secret) < MinSecretLen {
+		return nil, fmt.Errorf("service secret must be at least %d bytes", MinSecretLen)
+	}
+	return &Signer{secret: []byte(secret)}, nil
+}
+
+func (s *Signer) Sign(req *http.Request, body []byte) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderNonce, hex.EncodeToString(nonce))
+	req.Header.Set(HeaderSignature, signatureVersion+s.mac(req, body))
+}
+
+// Verify проверяет подпись и то, что время подписи отличается от now не больше чем на window.
+func (s *Signer) Verify(req *http.Request, body []byte, now time.Time, window time.Duration) error {
+	sig := req.Header.Get(HeaderSignature)
+	tsHeader := req.Header.Get(HeaderTimestamp)
+	if sig == "" || tsHeader == "" || req.Header.Get(HeaderNonce) == "" {
+		return ErrSignatureMissing
+	}
+
+	ts, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew > window || skew < -window {
+		return ErrSignatureExpired
+	}
+
+	got, ok := strings.CutPrefix(sig, signatureVersion)
+	if !ok || !hmac.Equal([]byte(got), []byte(s.mac(req, body))) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (s *Signer) mac(req *http.Request, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		req.Header.Get(HeaderTimestamp),
+		req.Header.Get(HeaderNonce),
+		req.Header.Get(shared.UserIDHeader),
+		req.Header.Get(shared.OwnerScopeHeader),
+		req.Header.Get("If-Match"),
+		req.Header.Get(shared.IdempotencyKeyHeader),
+		req.Header.Get("Content-Type"),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	m := hmac.New(sha256.New, s.secret)
+	m.Write([]byte(canonical))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// NonceCache запоминает nonce подписанных запросов на время окна,
+// чтобы один и тот же запрос нельзя было повторить внутри окна.
+type NonceCache struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+func NewNonceCache(ttl time.Duration) *NonceCache {
+	return &NonceCache{ttl: ttl, seen: map[string]time.Time{}}
+}
+
+// Use возвращает false, если nonce уже встречался.
+func (c *NonceCache) Use(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.pruned) > c.ttl {
+		for n, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, n)
+			}
+		}
+		c.pruned = now
+	}
+
+	if exp, ok := c.seen[nonce]; ok && now.Before(exp) {
+		return false
+	}
+	// Запрос с допустимым отклонением времени может прийти в течение 2*ttl
+	c.seen[nonce] = now.Add(2 * c.ttl)
+	return true
+}
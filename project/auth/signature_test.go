@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"errors"
+	"myproject/project/shared"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestNewSignerShortSecret(t *testing.T) {
+	if _, err := NewSigner(strings.Repeat("x", MinSecretLen-1)); err == nil {
+		t.Fatal("short secret accepted")
+	}
+}
+
+func TestSignerVerify(t *testing.T) {
+	signer, err := NewSigner(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewSigner(strings.Repeat("z", MinSecretLen))
+	body := []byte(`{"title":"t"}`)
+	window := time.Minute
+
+	tests := []struct {
+		name   string
+		tamper func(r *http.Request) ([]byte, time.Time)
+		verify *Signer
+		want   error
+	}{
+		{name: "valid", want: nil},
+		{name: "other secret", verify: other, want: ErrSignatureInvalid},
+		{name: "changed body", tamper: func(r *http.Request) ([]byte, time.Time) {
+			return []byte(`{"title":"x"}`), time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "changed method", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Method = http.MethodDelete
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "changed query", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.URL.RawQuery = "limit=500"
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "changed user", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set(shared.UserIDHeader, "2")
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "added owner scope", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set(shared.OwnerScopeHeader, shared.OwnerScopeAll)
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "changed If-Match", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set("If-Match", `"4"`)
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "changed idempotency key", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set(shared.IdempotencyKeyHeader, "other")
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "changed content type", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set("Content-Type", "text/plain")
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "changed nonce", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set(HeaderNonce, "00")
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "unknown version", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set(HeaderSignature, strings.Replace(r.Header.Get(HeaderSignature), "v1=", "v2=", 1))
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "bad timestamp", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Set(HeaderTimestamp, "yesterday")
+			return body, time.Now()
+		}, want: ErrSignatureInvalid},
+		{name: "too old", tamper: func(r *http.Request) ([]byte, time.Time) {
+			return body, time.Now().Add(2 * window)
+		}, want: ErrSignatureExpired},
+		{name: "from the future", tamper: func(r *http.Request) ([]byte, time.Time) {
+			return body, time.Now().Add(-2 * window)
+		}, want: ErrSignatureExpired},
+		{name: "missing signature", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Del(HeaderSignature)
+			return body, time.Now()
+		}, want: ErrSignatureMissing},
+		{name: "missing nonce", tamper: func(r *http.Request) ([]byte, time.Time) {
+			r.Header.Del(HeaderNonce)
+			return body, time.Now()
+		}, want: ErrSignatureMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tasks?limit=10", nil)
+			req.Header.Set(shared.UserIDHeader, "1")
+			req.Header.Set("If-Match", `"3"`)
+			req.Header.Set(shared.IdempotencyKeyHeader, "key-1")
+			req.Header.Set("Content-Type", "application/json")
+			signer.Sign(req, body)
+
+			got, now := body, time.Now()
+			if tt.tamper != nil {
+				got, now = tt.tamper(req)
+			}
+			verify := signer
+			if tt.verify != nil {
+				verify = tt.verify
+			}
+			if err := verify.Verify(req, got, now, window); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignerUniqueNonce(t *testing.T) {
+	signer, _ := NewSigner(testSecret)
+	seen := map[string]bool{}
+	for range 100 {
+		req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		signer.Sign(req, nil)
+		nonce := req.Header.Get(HeaderNonce)
+		if seen[nonce] {
+			t.Fatalf("nonce %s repeated", nonce)
+		}
+		seen[nonce] = true
+	}
+}
+
+func TestNonceCacheReplay(t *testing.T) {
+	ttl := time.Minute
+	c := NewNonceCache(ttl)
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name  string
+		nonce string
+		at    time.Time
+		want  bool
+	}{
+		{"first use", "a", now, true},
+		{"replay", "a", now.Add(time.Second), false},
+		{"other nonce", "b", now, true},
+		{"replay within skew", "a", now.Add(2*ttl - time.Second), false},
+		{"after expiry", "a", now.Add(2*ttl + time.Second), true},
+		{"replay after reuse", "a", now.Add(2*ttl + 2*time.Second), false},
+	}
+	for _, tt := range tests {
+		if got := c.Use(tt.nonce, tt.at); got != tt.want {
+			t.Errorf("%s: Use(%q) = %v, want %v", tt.name, tt.nonce, got, tt.want)
+		}
+	}
+}
+
+func TestSignedTimestampIsCurrent(t *testing.T) {
+	signer, _ := NewSigner(testSecret)
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	before := time.Now().Unix()
+	signer.Sign(req, nil)
+	ts, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil || ts < before || ts > time.Now().Unix() {
+		t.Fatalf("timestamp %q not current", req.Header.Get(HeaderTimestamp))
+	}
+}
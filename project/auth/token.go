@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Claims - полезная нагрузка JWT.
+type Claims struct {
+	UserID    int    `json:"sub"`
+	Username  string `json:"name"`
+	Role      Role   `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// MinSecretLen - минимальная длина секретов HMAC (JWT и подписи запросов).
+const MinSecretLen = 32
+
+// Issuer выпускает и проверяет JWT, подписанные HS256.
+type Issuer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewIssuer(secret string, ttl time.Duration) (*Issuer, error) {
+	if len(secret) < MinSecretLen {
+		return nil, fmt.Errorf("auth secret must be at least %d bytes", MinSecretLen)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("token ttl must be positive")
+	}
+	return &Issuer{secret: []byte(secret), ttl: ttl}, nil
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func (i *Issuer) Issue(userID int, username string, role Role) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(i.ttl)
+	payload, err := json.Marshal(Claims{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: exp.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	signing := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signing + "." + i.sign(signing), exp, nil
+}
+
+func (i *Issuer) Verify(token string) (Claims, error) {
+	var c Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return c, ErrInvalidToken
+	}
+	expected := i.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return c, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return c, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &c); err != nil || c.UserID <= 0 {
+		return c, ErrInvalidToken
+	}
+	if time.Now().Unix() >= c.ExpiresAt {
+		return c, ErrTokenExpired
+	}
+	return c, nil
+}
+
+func (i *Issuer) sign(s string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
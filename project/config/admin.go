@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"myproject/project/shared"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Handler отдаёт активный конфиг в JSON с ключами как в YAML.
+// Секреты и пароль в строке подключения к БД скрываются.
+func (r *Reloader[T, P]) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cfg, err := redact(r.Current())
+		if err != nil {
+			r.log.With(req.Context()).ERROR(fmt.Sprintf("config: redact failed: %v", err))
+			shared.WriteInternalError(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]any{
+			"path":      r.path,
+			"loaded_at": r.loadedAt.Load().UTC().Format(time.RFC3339),
+			"config":    cfg,
+		})
+	})
+}
+
+// redact переводит cfg в дерево map через YAML, чтобы сохранить ключи
+// и формат длительностей, и скрывает в нём секреты.
+func redact(cfg any) (map[string]any, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	redactTree(tree)
+	return tree, nil
+}
+
+func redactTree(tree map[string]any) {
+	for k, v := range tree {
+		switch v := v.(type) {
+		case map[string]any:
+			redactTree(v)
+		case string:
+			switch k {
+			case "secret":
+				if v != "" {
+					tree[k] = redacted
+				}
+			case "database_url":
+				tree[k] = redactDSN(v)
+			}
+		}
+	}
+}
+
+// redactDSN скрывает пароль в URL подключения. Строку в формате
+// "key=value" разбирать ненадёжно, поэтому она скрывается целиком.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return redacted
+}
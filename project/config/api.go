@@ -0,0 +1,103 @@
+package config
+
+import (
+	"time"
+
+	"myproject/project/shared"
+)
+
+// API - конфигурация api-service.
+type API struct {
+	Listen    Listen    `yaml:"listen"`
+	DBService DBService `yaml:"db_service"`
+	Auth      struct {
+		Secret   string        `yaml:"secret" env:"JWT_SECRET,AUTH_SECRET"`
+		TokenTTL time.Duration `yaml:"token_ttl" env:"AUTH_TOKEN_TTL"`
+		// RoleCacheTTL - сколько api-service доверяет прочитанной роли; 0 - читать на каждый запрос
+		RoleCacheTTL time.Duration `yaml:"role_cache_ttl" env:"AUTH_ROLE_CACHE_TTL"`
+	} `yaml:"auth"`
+	Timeouts shared.Timeouts      `yaml:"timeouts"`
+	Log      shared.LogConfig     `yaml:"log"`
+	Tracing  shared.TracingConfig `yaml:"tracing"`
+	Server   shared.ServerConfig  `yaml:"server"`
+}
+
+// Listen - адрес, на котором сервис принимает запросы.
+type Listen struct {
+	Addr string `yaml:"addr" env:"LISTEN_ADDR"`
+}
+
+// DBService - клиент db-service.
+type DBService struct {
+	URL string `yaml:"url" env:"DB_SERVICE_URL"`
+	// Общий секрет для подписи запросов к db-service
+	Secret string `yaml:"secret" env:"SERVICE_SECRET"`
+	// TLS включает mTLS: клиентский сертификат, ключ и CA сервера
+	TLS struct {
+		CertFile string `yaml:"cert_file"`
+		KeyFile  string `yaml:"key_file"`
+		CAFile   string `yaml:"ca_file"`
+	} `yaml:"tls"`
+	Retry   shared.RetryConfig   `yaml:"retry"`
+	Breaker shared.BreakerConfig `yaml:"breaker"`
+}
+
+// DefaultAPI возвращает значения по умолчанию, поверх которых читается файл.
+func DefaultAPI() *API {
+	cfg := &API{
+		Listen:    Listen{Addr: ":8080"},
+		DBService: DBService{URL: "http://localhost:8081"},
+		Server:    shared.ServerConfig{}.WithDefaults(),
+	}
+	cfg.Auth.TokenTTL = 24 * time.Hour
+	cfg.Auth.RoleCacheTTL = 10 * time.Second
+	return cfg
+}
+
+func (cfg *API) Validate() error {
+	var c checker
+	c.addr("listen.addr", cfg.Listen.Addr)
+
+	tls := cfg.DBService.TLS
+	if u := c.httpURL("db_service.url", cfg.DBService.URL); u != nil && tls.CertFile != "" && u.Scheme != "https" {
+		c.failf("db_service.url", "must be https when db_service.tls is set")
+	}
+	c.secret("db_service.secret", "SERVICE_SECRET", cfg.DBService.Secret)
+	c.pair("db_service.tls.cert_file", tls.CertFile, "db_service.tls.key_file", tls.KeyFile)
+	c.file("db_service.tls.cert_file", tls.CertFile)
+	c.file("db_service.tls.key_file", tls.KeyFile)
+	c.file("db_service.tls.ca_file", tls.CAFile)
+	retry, breaker := cfg.DBService.Retry, cfg.DBService.Breaker
+	if retry.MaxAttempts < 0 {
+		c.failf("db_service.retry.max_attempts", "must not be negative")
+	}
+	c.nonNegative("db_service.retry.base_delay", retry.BaseDelay)
+	c.nonNegative("db_service.retry.max_delay", retry.MaxDelay)
+	if breaker.FailureThreshold < 0 {
+		c.failf("db_service.breaker.failure_threshold", "must not be negative")
+	}
+	c.nonNegative("db_service.breaker.open_timeout", breaker.OpenTimeout)
+
+	c.secret("auth.secret", "JWT_SECRET", cfg.Auth.Secret)
+	c.positive("auth.token_ttl", cfg.Auth.TokenTTL)
+	c.nonNegative("auth.role_cache_ttl", cfg.Auth.RoleCacheTTL)
+
+	c.timeouts("timeouts", cfg.Timeouts)
+	c.log("log", cfg.Log)
+	c.tracing("tracing", cfg.Tracing)
+	c.server("server", cfg.Server, cfg.Timeouts)
+	return c.err()
+}
+
+// WithReloadable берёт из next уровень логов, дедлайны запросов, а также
+// адрес, повторы и circuit breaker db-service. Ограничения частоты запросов
+// и CORS в api-service не реализованы, поэтому и перечитывать их нечего.
+func (cfg *API) WithReloadable(next *API) *API {
+	merged := *cfg
+	merged.Log.Level = next.Log.Level
+	merged.Timeouts = next.Timeouts
+	merged.DBService.URL = next.DBService.URL
+	merged.DBService.Retry = next.DBService.Retry
+	merged.DBService.Breaker = next.DBService.Breaker
+	return &merged
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validator - конфиг, который умеет проверять себя после загрузки.
+type Validator interface {
+	Validate() error
+}
+
+// Load заполняет cfg по слоям: значения по умолчанию (уже записанные в cfg
+// вызывающим), затем YAML-файл path, затем переменные окружения из тегов env.
+// После этого вызывается cfg.Validate. Пустой path пропускает файл.
+func Load(path string, cfg Validator) error {
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return err
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(cfg).Elem(), os.LookupEnv); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			e.Path = path
+		}
+		return err
+	}
+	return nil
+}
+
+func loadFile(path string, cfg any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	// Неизвестный ключ - почти всегда опечатка, которая иначе молча игнорировалась бы
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv записывает в поля с тегом env значения заданных переменных окружения.
+// В теге можно перечислить несколько имён через запятую: берётся первое заданное.
+// Вложенные структуры обходятся рекурсивно.
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("env")
+		if tag == "" {
+			if fv.Kind() == reflect.Struct {
+				errs = append(errs, applyEnv(fv, lookup))
+			}
+			continue
+		}
+		var name, raw string
+		var ok bool
+		for _, name = range strings.Split(tag, ",") {
+			if raw, ok = lookup(name); ok {
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, strings.TrimSpace(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("env %s=%q: %w", name, raw, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
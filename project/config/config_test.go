@@ -0,0 +1,238 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testServiceSecret = "service-secret-0123456789abcdef0123"
+	testJWTSecret     = "jwt-secret-0123456789abcdef01234567"
+)
+
+// clearEnv снимает переменные, которые читает Load, на время теста.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"LISTEN_ADDR", "DB_SERVICE_URL", "SERVICE_SECRET", "JWT_SECRET", "AUTH_SECRET",
+		"AUTH_TOKEN_TTL", "AUTH_ROLE_CACHE_TTL", "LOG_LEVEL", "LOG_FORMAT",
+		"TRACING_EXPORTER", "TRACING_ENDPOINT", "DATABASE_URL", "MIGRATE_ON_START",
+		"DB_POOL_MAX_CONNS", "DB_POOL_MIN_CONNS",
+	} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAPIPrecedence(t *testing.T) {
+	yaml := `
+listen:
+  addr: ":9000"
+db_service:
+  url: "http://db:8081"
+auth:
+  token_ttl: 1h
+log:
+  level: warn
+`
+	tests := []struct {
+		name  string
+		env   map[string]string
+		check func(t *testing.T, cfg *API)
+	}{
+		{
+			name: "yaml over defaults",
+			check: func(t *testing.T, cfg *API) {
+				if cfg.Listen.Addr != ":9000" || cfg.DBService.URL != "http://db:8081" || cfg.Auth.TokenTTL != time.Hour || cfg.Log.Level != "warn" {
+					t.Errorf("yaml not applied: %+v", cfg)
+				}
+				if cfg.Auth.RoleCacheTTL != 10*time.Second {
+					t.Errorf("default role_cache_ttl lost: %s", cfg.Auth.RoleCacheTTL)
+				}
+			},
+		},
+		{
+			name: "env over yaml",
+			env:  map[string]string{"LISTEN_ADDR": ":9100", "AUTH_TOKEN_TTL": "15m", "LOG_LEVEL": " debug "},
+			check: func(t *testing.T, cfg *API) {
+				if cfg.Listen.Addr != ":9100" || cfg.Auth.TokenTTL != 15*time.Minute || cfg.Log.Level != "debug" {
+					t.Errorf("env not applied: %+v", cfg)
+				}
+				if cfg.DBService.URL != "http://db:8081" {
+					t.Errorf("unset env overrode yaml: %s", cfg.DBService.URL)
+				}
+			},
+		},
+		{
+			name: "first of several env names wins",
+			env:  map[string]string{"AUTH_SECRET": "auth-secret-0123456789abcdef0123456"},
+			check: func(t *testing.T, cfg *API) {
+				if cfg.Auth.Secret != testJWTSecret {
+					t.Errorf("auth.secret = %q, want JWT_SECRET", cfg.Auth.Secret)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("SERVICE_SECRET", testServiceSecret)
+			t.Setenv("JWT_SECRET", testJWTSecret)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := DefaultAPI()
+			if err := Load(writeConfig(t, yaml), cfg); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadAPIFallbackEnvName(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SERVICE_SECRET", testServiceSecret)
+	t.Setenv("AUTH_SECRET", testJWTSecret)
+	cfg := DefaultAPI()
+	if err := Load("", cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.Secret != testJWTSecret {
+		t.Errorf("auth.secret = %q, want AUTH_SECRET", cfg.Auth.Secret)
+	}
+}
+
+func TestLoadDBEnvOverYAML(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SERVICE_SECRET", testServiceSecret)
+	t.Setenv("DATABASE_URL", "postgres://app@db:5432/tasks")
+	t.Setenv("DB_POOL_MAX_CONNS", "20")
+	t.Setenv("MIGRATE_ON_START", "true")
+	path := writeConfig(t, `
+database_url: "postgres://other@localhost:5432/tasks"
+pool:
+  max_conns: 4
+  min_conns: 2
+`)
+	cfg := DefaultDB()
+	if err := Load(path, cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://app@db:5432/tasks" || cfg.Pool.MaxConns != 20 || cfg.Pool.MinConns != 2 || !cfg.MigrateOnStart {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+		// want - подстроки, которые должны быть в ошибке
+		want []string
+		// invalid - ошибка проверки (*Error), а не разбора
+		invalid bool
+	}{
+		{
+			name: "unknown yaml key",
+			yaml: "listen:\n  adress: \":80\"\n",
+			want: []string{"adress"},
+		},
+		{
+			name: "bad env duration",
+			env:  map[string]string{"AUTH_TOKEN_TTL": "soon"},
+			want: []string{"AUTH_TOKEN_TTL"},
+		},
+		{
+			name:    "missing secrets",
+			env:     map[string]string{"SERVICE_SECRET": "", "JWT_SECRET": ""},
+			want:    []string{"db_service.secret: required, set SERVICE_SECRET", "auth.secret: required, set JWT_SECRET"},
+			invalid: true,
+		},
+		{
+			name:    "placeholder secret",
+			env:     map[string]string{"JWT_SECRET": "change-me-change-me-change-me-change-me"},
+			want:    []string{"auth.secret: is a placeholder"},
+			invalid: true,
+		},
+		{
+			name:    "placeholder secret in yaml",
+			yaml:    "db_service:\n  secret: \"CHANGE-ME-please-0123456789abcdef\"\n",
+			env:     map[string]string{"SERVICE_SECRET": ""},
+			want:    []string{"db_service.secret: is a placeholder"},
+			invalid: true,
+		},
+		{
+			name:    "short secret",
+			env:     map[string]string{"SERVICE_SECRET": "short"},
+			want:    []string{"db_service.secret: must be at least 32 bytes"},
+			invalid: true,
+		},
+		{
+			name:    "all problems reported",
+			yaml:    "listen:\n  addr: \"nowhere\"\ndb_service:\n  url: \"ftp://db\"\n",
+			env:     map[string]string{"AUTH_ROLE_CACHE_TTL": "-1s"},
+			want:    []string{"listen.addr", "db_service.url", "auth.role_cache_ttl"},
+			invalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("SERVICE_SECRET", testServiceSecret)
+			t.Setenv("JWT_SECRET", testJWTSecret)
+			for k, v := range tt.env {
+				if v == "" {
+					os.Unsetenv(k)
+					continue
+				}
+				t.Setenv(k, v)
+			}
+			path := writeConfig(t, tt.yaml)
+			err := Load(path, DefaultAPI())
+			if err == nil {
+				t.Fatal("Load succeeded")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not mention %q", err, w)
+				}
+			}
+			var e *Error
+			if got := errors.As(err, &e); got != tt.invalid {
+				t.Fatalf("errors.As(*Error) = %v, want %v", got, tt.invalid)
+			}
+			if e != nil && (e.Path != path || len(e.Problems) < len(tt.want)) {
+				t.Errorf("Error = %+v", e)
+			}
+		})
+	}
+}
+
+// Файлы из репозитория должны загружаться, когда секреты заданы окружением.
+func TestLoadShippedConfigs(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SERVICE_SECRET", testServiceSecret)
+	t.Setenv("JWT_SECRET", testJWTSecret)
+
+	if err := Load("../api-service/server/config.yaml", DefaultAPI()); err != nil {
+		t.Errorf("api-service config: %v", err)
+	}
+	if err := Load("../db-service/server/database.yml", DefaultDB()); err != nil {
+		t.Errorf("db-service config: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"time"
+
+	"myproject/project/shared"
+)
+
+// DB - конфигурация db-service.
+type DB struct {
+	Listen         Listen `yaml:"listen"`
+	DatabaseURL    string `yaml:"database_url" env:"DATABASE_URL"`
+	MigrateOnStart bool   `yaml:"migrate_on_start" env:"MIGRATE_ON_START"`
+	// Pool - пул соединений с PostgreSQL и параметры сессии
+	Pool shared.PoolConfig `yaml:"pool"`
+	// Задачи из корзины старше TrashRetention удаляются безвозвратно.
+	// 0 отключает фоновую очистку.
+	TrashRetention     time.Duration `yaml:"trash_retention"`
+	TrashPurgeInterval time.Duration `yaml:"trash_purge_interval"`
+	// ServiceAuth - проверка HMAC-подписи запросов от api-service
+	ServiceAuth struct {
+		Secret string        `yaml:"secret" env:"SERVICE_SECRET"`
+		Window time.Duration `yaml:"window"`
+	} `yaml:"service_auth"`
+	// TLS включает mTLS, если заданы сертификат, ключ и CA клиентов
+	TLS struct {
+		CertFile     string `yaml:"cert_file"`
+		KeyFile      string `yaml:"key_file"`
+		ClientCAFile string `yaml:"client_ca_file"`
+	} `yaml:"tls"`
+	// Uniqueness - необязательные правила уникальности задач (409 при нарушении)
+	Uniqueness shared.UniquenessRules `yaml:"uniqueness"`
+	// Timeouts - дедлайны обработки запросов, включая запросы к БД
+	Timeouts shared.Timeouts `yaml:"timeouts"`
+	// Log - уровень, формат и скрываемые поля логов
+	Log shared.LogConfig `yaml:"log"`
+	// Tracing - экспорт спанов OpenTelemetry
+	Tracing shared.TracingConfig `yaml:"tracing"`
+	// Server - таймауты HTTP-сервера и порядок остановки
+	Server shared.ServerConfig `yaml:"server"`
+}
+
+// DefaultDB возвращает значения по умолчанию, поверх которых читается файл.
+func DefaultDB() *DB {
+	cfg := &DB{
+		Listen:             Listen{Addr: ":8081"},
+		Pool:               shared.PoolConfig{ApplicationName: "db-service", ConnectTimeout: 30 * time.Second},
+		TrashPurgeInterval: time.Hour,
+		Server:             shared.ServerConfig{}.WithDefaults(),
+	}
+	cfg.ServiceAuth.Window = 5 * time.Minute
+	return cfg
+}
+
+func (cfg *DB) Validate() error {
+	var c checker
+	c.addr("listen.addr", cfg.Listen.Addr)
+
+	if cfg.DatabaseURL == "" {
+		c.failf("database_url", "required")
+	} else {
+		c.dsn("database_url", cfg.DatabaseURL)
+	}
+	if p := cfg.Pool; p.MaxConns < 0 || p.MinConns < 0 {
+		c.failf("pool", "max_conns and min_conns must not be negative")
+	} else if p.MaxConns > 0 && p.MinConns > p.MaxConns {
+		c.failf("pool.min_conns", "must not exceed max_conns (%d)", p.MaxConns)
+	}
+	c.nonNegative("pool.max_conn_lifetime", cfg.Pool.MaxConnLifetime)
+	c.nonNegative("pool.max_conn_idle_time", cfg.Pool.MaxConnIdleTime)
+	c.nonNegative("pool.health_check_period", cfg.Pool.HealthCheckPeriod)
+	c.nonNegative("pool.connect_timeout", cfg.Pool.ConnectTimeout)
+	// statement_timeout задаётся в миллисекундах, меньшее значение стало бы 0 - без ограничения
+	if d := cfg.Pool.StatementTimeout; d < 0 || (d > 0 && d < time.Millisecond) {
+		c.failf("pool.statement_timeout", "must be 0 or at least 1ms, got %s", d)
+	}
+
+	c.nonNegative("trash_retention", cfg.TrashRetention)
+	c.positive("trash_purge_interval", cfg.TrashPurgeInterval)
+	c.secret("service_auth.secret", "SERVICE_SECRET", cfg.ServiceAuth.Secret)
+	c.positive("service_auth.window", cfg.ServiceAuth.Window)
+
+	tls := cfg.TLS
+	c.pair("tls.cert_file", tls.CertFile, "tls.key_file", tls.KeyFile)
+	c.file("tls.cert_file", tls.CertFile)
+	c.file("tls.key_file", tls.KeyFile)
+	c.file("tls.client_ca_file", tls.ClientCAFile)
+
+	c.timeouts("timeouts", cfg.Timeouts)
+	c.log("log", cfg.Log)
+	c.tracing("tracing", cfg.Tracing)
+	c.server("server", cfg.Server, cfg.Timeouts)
+	return c.err()
+}
+
+// WithReloadable берёт из next уровень логов и дедлайны запросов.
+// Ограничений частоты запросов и CORS в db-service нет.
+func (cfg *DB) WithReloadable(next *DB) *DB {
+	merged := *cfg
+	merged.Log.Level = next.Log.Level
+	merged.Timeouts = next.Timeouts
+	return &merged
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	logger "myproject/project/Logger"
+)
+
+// WatchInterval - как часто Watch проверяет, изменился ли файл конфигурации.
+const WatchInterval = 5 * time.Second
+
+// Reloadable - конфиг, часть настроек которого применяется без перезапуска.
+type Reloadable[T any] interface {
+	*T
+	Validator
+	// WithReloadable возвращает копию конфига, в которой перезагружаемые
+	// настройки взяты из next, а остальные оставлены как есть.
+	WithReloadable(next *T) *T
+}
+
+// Reloader хранит активный конфиг сервиса и перечитывает его файл.
+// Перезагружаемые настройки применяются атомарно: читатели Current видят
+// либо старый конфиг целиком, либо новый. Изменения остальных настроек
+// отклоняются с предупреждением в логе и вступают в силу после перезапуска.
+type Reloader[T any, P Reloadable[T]] struct {
+	path     string
+	defaults func() P
+	log      *logger.Logger
+
+	mu       sync.Mutex
+	current  atomic.Pointer[T]
+	loadedAt atomic.Pointer[time.Time]
+	onChange []func(P)
+}
+
+// NewReloader создаёт Reloader с уже загруженным конфигом cfg. При перезагрузке
+// файл path читается поверх defaults() так же, как при запуске (Load).
+func NewReloader[T any, P Reloadable[T]](path string, cfg P, defaults func() P, log *logger.Logger) *Reloader[T, P] {
+	r := &Reloader[T, P]{path: path, defaults: defaults, log: log}
+	r.store(cfg)
+	return r
+}
+
+func (r *Reloader[T, P]) store(cfg P) {
+	now := time.Now()
+	r.current.Store((*T)(cfg))
+	r.loadedAt.Store(&now)
+}
+
+// Current возвращает активный конфиг. Его нельзя изменять.
+func (r *Reloader[T, P]) Current() P {
+	return r.current.Load()
+}
+
+// OnChange регистрирует fn, которая применяет новый конфиг после каждой
+// успешной перезагрузки. Вызывается до начала Watch.
+func (r *Reloader[T, P]) OnChange(fn func(P)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+// Reload перечитывает файл и переменные окружения. Если новый конфиг не
+// проходит проверку, активный не меняется.
+func (r *Reloader[T, P]) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.defaults()
+	if err := Load(r.path, next); err != nil {
+		return err
+	}
+	cur := r.Current()
+	merged := P(cur.WithReloadable((*T)(next)))
+	for _, field := range diff(reflect.ValueOf(merged).Elem(), reflect.ValueOf(next).Elem(), "") {
+		r.log.WARN(fmt.Sprintf("config reload: %s changed but requires a restart, keeping the current value", field))
+	}
+	changed := diff(reflect.ValueOf(cur).Elem(), reflect.ValueOf(merged).Elem(), "")
+	if len(changed) == 0 {
+		return nil
+	}
+	r.store(merged)
+	for _, fn := range r.onChange {
+		fn(merged)
+	}
+	r.log.INFO(fmt.Sprintf("config reload: applied %s", strings.Join(changed, ", ")))
+	return nil
+}
+
+// Watch вызывает Reload при каждом SIGHUP и при изменении файла конфигурации
+// (проверка раз в interval) до отмены ctx. Ошибки перезагрузки пишутся в лог.
+func (r *Reloader[T, P]) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(interval)
+	last := r.stat()
+	go func() {
+		defer signal.Stop(hup)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				last = r.stat()
+			case <-ticker.C:
+				// Файл может на мгновение пропасть, пока редактор или
+				// оркестратор подменяет его; тогда ждём следующей проверки
+				st := r.stat()
+				if st == (fileState{}) || st == last {
+					continue
+				}
+				last = st
+			}
+			if err := r.Reload(); err != nil {
+				r.log.ERROR(fmt.Sprintf("config reload failed, keeping the current config: %v", err))
+			}
+		}
+	}()
+}
+
+// fileState - признаки изменения файла конфигурации.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (r *Reloader[T, P]) stat() fileState {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// diff возвращает ключи YAML полей, значения которых в a и b различаются.
+func diff(a, b reflect.Value, prefix string) []string {
+	var fields []string
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fa, fb := a.Field(i), b.Field(i)
+		switch {
+		case fa.Kind() == reflect.Struct:
+			fields = append(fields, diff(fa, fb, name)...)
+		case !reflect.DeepEqual(fa.Interface(), fb.Interface()):
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
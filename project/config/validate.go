@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"myproject/project/auth"
+	"myproject/project/shared"
+)
+
+// placeholderSecret - признак секрета-заглушки из примеров конфигурации.
+const placeholderSecret = "change-me"
+
+// Error перечисляет все найденные ошибки конфигурации, а не только первую.
+type Error struct {
+	Path     string
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	where := "config"
+	if e.Path != "" {
+		where = "config " + e.Path
+	}
+	return fmt.Sprintf("invalid %s:\n  - %s", where, strings.Join(e.Problems, "\n  - "))
+}
+
+// checker накапливает ошибки проверки. Поле указывается ключом YAML.
+type checker struct {
+	problems []string
+}
+
+func (c *checker) failf(field, format string, args ...any) {
+	c.problems = append(c.problems, field+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *checker) err() error {
+	if len(c.problems) == 0 {
+		return nil
+	}
+	return &Error{Problems: c.problems}
+}
+
+func (c *checker) addr(field, addr string) {
+	if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+		c.failf(field, "must be host:port, got %q", addr)
+	}
+}
+
+func (c *checker) httpURL(field, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.failf(field, "must be an http(s) URL, got %q", raw)
+		return nil
+	}
+	return u
+}
+
+// dsn проверяет строку подключения PostgreSQL: URL postgres:// или пары key=value.
+// Полный разбор делает pgx при подключении; здесь ловятся явные ошибки,
+// а текст не попадает в сообщение, чтобы не раскрыть пароль.
+func (c *checker) dsn(field, dsn string) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err != nil || u.Host == "" && u.Query().Get("host") == "" {
+			c.failf(field, "must be a valid postgres:// URL")
+		}
+		return
+	}
+	if !strings.Contains(dsn, "=") {
+		c.failf(field, "must be a postgres:// URL or key=value pairs")
+	}
+}
+
+// secret проверяет секрет HMAC. env - переменная, через которую его принято задавать:
+// в репозитории секреты пустые, чтобы сервис не запустился с общеизвестным ключом.
+func (c *checker) secret(field, env, secret string) {
+	switch {
+	case secret == "":
+		c.failf(field, "required, set %s", env)
+	case strings.Contains(strings.ToLower(secret), placeholderSecret):
+		c.failf(field, "is a placeholder, set a real secret in %s", env)
+	case len(secret) < auth.MinSecretLen:
+		c.failf(field, "must be at least %d bytes", auth.MinSecretLen)
+	}
+}
+
+func (c *checker) positive(field string, d time.Duration) {
+	if d <= 0 {
+		c.failf(field, "must be positive, got %s", d)
+	}
+}
+
+func (c *checker) nonNegative(field string, d time.Duration) {
+	if d < 0 {
+		c.failf(field, "must not be negative, got %s", d)
+	}
+}
+
+// file проверяет, что необязательный файл, если задан, существует.
+func (c *checker) file(field, path string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		c.failf(field, "%v", err)
+	}
+}
+
+// pair проверяет поля, которые задаются только вместе (сертификат и ключ).
+func (c *checker) pair(fieldA, a, fieldB, b string) {
+	if (a == "") != (b == "") {
+		c.failf(fieldA, "%s and %s must be set together", fieldA, fieldB)
+	}
+}
+
+func (c *checker) timeouts(prefix string, t shared.Timeouts) {
+	c.nonNegative(prefix+".default", t.Default)
+	for route, d := range t.Routes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			c.failf(prefix+".routes", "key %q must be \"METHOD /route/template\"", route)
+		}
+		c.positive(fmt.Sprintf("%s.routes[%q]", prefix, route), d)
+	}
+}
+
+func (c *checker) log(prefix string, l shared.LogConfig) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); l.Level != "" && err != nil {
+		c.failf(prefix+".level", "must be debug, info, warn or error, got %q", l.Level)
+	}
+	if l.Format != "" && l.Format != "text" && l.Format != "json" {
+		c.failf(prefix+".format", "must be text or json, got %q", l.Format)
+	}
+	for _, sink := range []struct {
+		field string
+		cfg   shared.FileSinkConfig
+	}{{prefix + ".file", l.File}, {prefix + ".access_log", l.AccessLog}} {
+		if sink.cfg.MaxSizeMB < 0 || sink.cfg.MaxBackups < 0 {
+			c.failf(sink.field, "max_size_mb and max_backups must not be negative")
+		}
+		c.nonNegative(sink.field+".max_age", sink.cfg.MaxAge)
+	}
+}
+
+func (c *checker) tracing(prefix string, t shared.TracingConfig) {
+	switch t.Exporter {
+	case "", shared.TraceExporterNone, shared.TraceExporterOTLP, shared.TraceExporterStdout:
+	case shared.TraceExporterFile:
+		if t.File == "" {
+			c.failf(prefix+".file", "required when exporter is file")
+		}
+	default:
+		c.failf(prefix+".exporter", "must be none, otlp, stdout or file, got %q", t.Exporter)
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		c.failf(prefix+".sample_ratio", "must be between 0 and 1, got %v", t.SampleRatio)
+	}
+}
+
+// server проверяет таймауты сервера и то, что запись ответа не обрывается
+// раньше дедлайна самого долгого маршрута.
+func (c *checker) server(prefix string, s shared.ServerConfig, t shared.Timeouts) {
+	c.positive(prefix+".read_timeout", s.ReadTimeout)
+	c.positive(prefix+".read_header_timeout", s.ReadHeaderTimeout)
+	c.positive(prefix+".write_timeout", s.WriteTimeout)
+	c.positive(prefix+".idle_timeout", s.IdleTimeout)
+	c.nonNegative(prefix+".drain_delay", s.DrainDelay)
+	c.positive(prefix+".shutdown_timeout", s.ShutdownTimeout)
+
+	longest := t.For("", "")
+	for _, d := range t.Routes {
+		longest = max(longest, d)
+	}
+	if s.WriteTimeout > 0 && s.WriteTimeout <= longest {
+		c.failf(prefix+".write_timeout", "must be greater than the longest request timeout (%s)", longest)
+	}
+}
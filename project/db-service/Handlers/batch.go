@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	databaseconnect "myproject/project/db-service/database_connect"
+	"myproject/project/db-service/database_connect/service"
+	"myproject/project/shared"
+	"net/http"
+)
+
+func (h *Handler) BatchPost(w http.ResponseWriter, r *http.Request) {
+	var tasks []shared.Task
+	mode, ok := h.decodeBatch(w, r, "BatchPost", &tasks)
+	if !ok {
+		return
+	}
+	items, err := h.s.CreateTasks(r.Context(), tasks, mode)
+	h.writeBatch(w, r, "BatchPost", mode, http.StatusCreated, items, err, func(i int) int { return 0 })
+}
+
+func (h *Handler) BatchPatch(w http.ResponseWriter, r *http.Request) {
+	var patches []shared.BatchPatch
+	mode, ok := h.decodeBatch(w, r, "BatchPatch", &patches)
+	if !ok {
+		return
+	}
+	items, err := h.s.UpdateTasks(r.Context(), patches, mode)
+	h.writeBatch(w, r, "BatchPatch", mode, http.StatusOK, items, err, func(i int) int { return patches[i].ID })
+}
+
+func (h *Handler) BatchDelete(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	mode, ok := h.decodeBatch(w, r, "BatchDelete", &ids)
+	if !ok {
+		return
+	}
+	items, err := h.s.DeleteTasks(r.Context(), ids, mode)
+	h.writeBatch(w, r, "BatchDelete", mode, http.StatusOK, items, err, func(i int) int { return ids[i] })
+}
+
+func (h *Handler) decodeBatch(w http.ResponseWriter, r *http.Request, op string, dst any) (string, bool) {
+	log := h.log.With(r.Context())
+	mode, err := shared.ParseBatchMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("%s handler: %v", op, err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return "", false
+	}
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		log.ERROR(fmt.Sprintf("Wrong Content type in %s Handler(db-service)", op))
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return "", false
+	}
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		log.ERROR(fmt.Sprintf("Wrong format of JSON in %s handler(db-service):%v", op, err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return "", false
+	}
+	return mode, true
+}
+
+func batchStatus(err error, success int) int {
+	switch {
+	case err == nil:
+		return success
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, databaseconnect.ErrConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func batchCode(status int) string {
+	if status == http.StatusBadRequest {
+		return shared.CodeValidation
+	}
+	return shared.CodeForStatus(status)
+}
+
+func (h *Handler) writeBatch(w http.ResponseWriter, r *http.Request, op, mode string, success int, items []databaseconnect.BatchItem, err error, idOf func(int) int) {
+	log := h.log.With(r.Context())
+	if err != nil {
+		status := batchStatus(err, success)
+		log.ERROR(fmt.Sprintf("%s handler: batch rejected: %v", op, err))
+		if status == http.StatusInternalServerError {
+			shared.WriteError(w, r, status, shared.CodeInternal, "internal server error")
+			return
+		}
+		resp := shared.ErrorResponse{Code: batchCode(status), Message: err.Error(), Details: map[string]any{}}
+		var be *databaseconnect.BatchError
+		if errors.As(err, &be) {
+			resp.Details["index"] = be.Index
+		}
+		var conflict *databaseconnect.ConflictError
+		if errors.As(err, &conflict) {
+			resp.Details["task_id"] = conflict.TaskID
+		}
+		shared.WriteErrorResponse(w, r, status, resp)
+		return
+	}
+
+	resp := shared.BatchResponse{Mode: mode, Results: make([]shared.BatchResult, len(items))}
+	failed := 0
+	for i, item := range items {
+		res := shared.BatchResult{Index: i, ID: idOf(i), Status: batchStatus(item.Err, success)}
+		if item.Err != nil {
+			failed++
+			res.Code = batchCode(res.Status)
+			res.Error = item.Err.Error()
+			if res.Status == http.StatusInternalServerError {
+				res.Error = "internal server error"
+			}
+		} else {
+			task := item.Task
+			res.ID = task.ID
+			res.Task = &task
+		}
+		resp.Results[i] = res
+	}
+
+	status := success
+	if mode == shared.BatchPartial {
+		status = http.StatusMultiStatus
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+	log.INFO(fmt.Sprintf("%s handler executed: %d item(s), %d failed", op, len(items), failed))
+}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	logger "myproject/project/Logger"
+	databaseconnect "myproject/project/db-service/database_connect"
 	"myproject/project/db-service/database_connect/service"
 	"myproject/project/shared"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -22,7 +24,24 @@ func NewHandler(service service.Service, log logger.Logger) *Handler {
 	return &Handler{service, log}
 }
 
+// writeConflict отвечает 409 с id задачи, с которой конфликтует запрос.
+func (h *Handler) writeConflict(w http.ResponseWriter, r *http.Request, err error) bool {
+	log := h.log.With(r.Context())
+	var conflict *databaseconnect.ConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	log.ERROR(fmt.Sprintf("conflict: %v", conflict))
+	shared.WriteErrorResponse(w, r, http.StatusConflict, shared.ErrorResponse{
+		Code:    shared.CodeConflict,
+		Message: conflict.Rule,
+		Details: map[string]any{"task_id": conflict.TaskID},
+	})
+	return true
+}
+
 func (h *Handler) GetTask(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
 	ctx := r.Context()
 
 	vars := mux.Vars(r)
@@ -30,148 +49,383 @@ func (h *Handler) GetTask(w http.ResponseWriter, r *http.Request) {
 
 	taskID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Invalid ID error(GetTask-handler):%v", err))
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Invalid ID error(GetTask-handler):%v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
 		return
 	}
 
 	task, err := h.s.GetTask(ctx, taskID)
 	if err != nil {
 		if errors.Is(err, service.ErrTaskNotFound) {
-			h.log.ERROR(fmt.Sprintf("task not found(GetTask-handler): %v", err))
-			http.Error(w, "task not found", http.StatusNotFound)
+			log.ERROR(fmt.Sprintf("task not found(GetTask-handler): %v", err))
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, "task not found")
 			return
 		}
-		h.log.ERROR(fmt.Sprintf("GetTask Handler(db-service) failed: %v", err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		log.ERROR(fmt.Sprintf("GetTask Handler(db-service) failed: %v", err))
+		shared.WriteInternalError(w, r)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", shared.ETag(task.Version))
 	if err := json.NewEncoder(w).Encode(task); err != nil {
-		h.log.ERROR(fmt.Sprintf("Json encoding error: %v", err))
-		http.Error(w, "JSON encoding error", http.StatusInternalServerError)
+		log.ERROR(fmt.Sprintf("Json encoding error: %v", err))
+		shared.WriteError(w, r, http.StatusInternalServerError, shared.CodeInternal, "JSON encoding error")
 		return
 	}
-	h.log.INFO("GetTask Handler executed successfully")
+	log.INFO("GetTask Handler executed successfully")
 }
 
 func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
 	var ID int
 	var erro error
 
 	ctx := r.Context()
 	if r.Header.Get("Content-Type") != "application/json" {
-		h.log.ERROR(fmt.Sprintf("Wrong Contetnt type in Post Handler(db-service): %v", erro))
-		http.Error(w, "Content-Type должен быть application/json", http.StatusUnsupportedMediaType)
+		log.ERROR(fmt.Sprintf("Wrong Contetnt type in Post Handler(db-service): %q", r.Header.Get("Content-Type")))
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
 		return
 	}
 	var Task shared.Task
 	err := json.NewDecoder(r.Body).Decode(&Task)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Wrong format of JSON in handler(db-service):%v", err))
-		http.Error(w, "неверный формат JSON", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Wrong format of JSON in handler(db-service):%v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
 		return
 	}
-	h.log.DEBUG(fmt.Sprintf("Post handler: received task: %+v", Task))
-	ID, erro = h.s.CreateTask(ctx, Task)
+	log.DEBUG("Post handler: received task", "body_bytes", r.ContentLength)
+	// С ключом идемпотентности повтор запроса возвращает исходный ответ
+	if key := r.Header.Get(shared.IdempotencyKeyHeader); key != "" {
+		var replayed bool
+		ID, replayed, erro = h.s.CreateTaskIdempotent(ctx, key, Task)
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+		}
+	} else {
+		ID, erro = h.s.CreateTask(ctx, Task)
+	}
 	if erro != nil {
+		if h.writeConflict(w, r, erro) {
+			return
+		}
 		switch {
 		case errors.Is(erro, service.ErrInvalidInput):
-			h.log.ERROR(fmt.Sprintf("Post handler: invalid input: %v", err))
-			http.Error(w, erro.Error(), http.StatusBadRequest)
+			log.ERROR(fmt.Sprintf("Post handler: invalid input: %v", erro))
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, erro.Error())
+		case errors.Is(erro, service.ErrIdempotencyKeyReused):
+			log.ERROR(fmt.Sprintf("Post handler: %v", erro))
+			shared.WriteError(w, r, http.StatusUnprocessableEntity, shared.CodeIdempotencyKeyReused, erro.Error())
 		default:
-			h.log.ERROR(fmt.Sprintf("Post handler: internal error: %v", err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			log.ERROR(fmt.Sprintf("Post handler: internal error: %v", erro))
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(shared.IDResponse{ID: int64(ID)})
-	h.log.INFO(fmt.Sprintf("Post handler: task created successfully, ID=%d", ID))
-	h.log.DEBUG(fmt.Sprintf("Post handler: response sent with ID=%d", ID))
+	log.INFO(fmt.Sprintf("Post handler: task created successfully, ID=%d", ID))
+	log.DEBUG(fmt.Sprintf("Post handler: response sent with ID=%d", ID))
 
 }
 func (h *Handler) AllTasks(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
 	ctx := r.Context()
 
-	tasks, err := h.s.GetAllTasks(ctx)
+	filter, err := shared.ParseTaskFilter(r.URL.Query())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("AllTasks handler: invalid query: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return
+	}
+	log.DEBUG(fmt.Sprintf("AllTasks handler: filter %+v", filter))
+
+	page, err := h.s.GetAllTasks(ctx, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptySlice):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(shared.TaskPage{Tasks: []shared.Task{}})
+			return
+		case errors.Is(err, service.ErrInvalidInput):
+			log.ERROR(fmt.Sprintf("AllTasks handler: invalid input: %v", err))
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+			return
+		default:
+			log.ERROR(fmt.Sprintf("AllTasks handler:internal error: %v", err))
+			shared.WriteInternalError(w, r)
+			return
+		}
+	}
+	log.INFO("AllTasks handler executed successfully")
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	ctx := r.Context()
 
+	q, err := shared.ParseSearchQuery(r.URL.Query())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Search handler: invalid query: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return
+	}
+	log.DEBUG("Search handler: received query", "query_len", len(q.Q), "limit", q.Limit, "offset", q.Offset)
+
+	results, err := h.s.SearchTasks(ctx, q)
 	if err != nil {
 		switch {
-		case errors.Is(err, service.ErrEmptySlice), errors.Is(err, service.ErrTooFewTasks):
+		case errors.Is(err, service.ErrInvalidInput):
+			log.ERROR(fmt.Sprintf("Search handler: invalid input: %v", err))
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		default:
+			log.ERROR(fmt.Sprintf("Search handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+	log.INFO("Search handler executed successfully")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shared.SearchResponse{Query: q.Q, Results: results})
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	log.DEBUG(fmt.Sprintf("Delete handler: got id param = %s", idStr))
+
+	taskID, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Invalid id: %d", taskID))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+
+	action := "delete"
+	if r.URL.Query().Get("purge") == "true" {
+		action = "purge"
+	}
+
+	err = h.s.ModifyTask(ctx, taskID, action)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTaskNotFound):
+			log.ERROR(fmt.Sprintf("Delete handler: task %d not found", taskID))
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, "task not found")
+		default:
+			log.ERROR(fmt.Sprintf("Delete handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	log.INFO(fmt.Sprintf("Delete handler executed successfully, action=%s", action))
+}
+
+func (h *Handler) Trash(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	ctx := r.Context()
 
+	filter, err := shared.ParseTaskFilter(r.URL.Query())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Trash handler: invalid query: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		return
+	}
+	filter.Trashed = true
+
+	page, err := h.s.GetAllTasks(ctx, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptySlice):
+			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode([]shared.Task{})
+			json.NewEncoder(w).Encode(shared.TaskPage{Tasks: []shared.Task{}})
+			return
+		case errors.Is(err, service.ErrInvalidInput):
+			log.ERROR(fmt.Sprintf("Trash handler: invalid input: %v", err))
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
 			return
 		default:
-			h.log.ERROR(fmt.Sprintf("AllTasks handler:internal error: %v", err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			log.ERROR(fmt.Sprintf("Trash handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
 			return
 		}
 	}
-	h.log.INFO("AllTasks handler executed successfully")
+	log.INFO("Trash handler executed successfully")
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(tasks)
+	json.NewEncoder(w).Encode(page)
 }
-func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	idStr := vars["id"]
-	h.log.DEBUG(fmt.Sprintf("Delete handler: got id param = %s", idStr))
+	log.DEBUG(fmt.Sprintf("Restore handler: got id param = %s", idStr))
 
 	taskID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Invalid id: %d", taskID))
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Invalid id: %d", taskID))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
 		return
 	}
 
-	err = h.s.ModifyTask(ctx, taskID, "delete")
+	task, err := h.s.RestoreTask(ctx, taskID)
 	if err != nil {
+		if h.writeConflict(w, r, err) {
+			return
+		}
 		switch {
 		case errors.Is(err, service.ErrTaskNotFound):
-			h.log.ERROR(fmt.Sprintf("Delete handler: task %d not found", taskID))
-			http.Error(w, "task not found", http.StatusNotFound)
+			log.ERROR(fmt.Sprintf("Restore handler: task %d not in trash", taskID))
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, "task not found in trash")
 		default:
-			h.log.ERROR(fmt.Sprintf("Delete handler: internal error: %v", err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			log.ERROR(fmt.Sprintf("Restore handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
+	log.INFO("Restore handler executed successfully")
 
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", shared.ETag(task.Version))
 	w.WriteHeader(http.StatusOK)
-	h.log.INFO("Delete handler executed successfully")
+	json.NewEncoder(w).Encode(task)
 }
 
 func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	log.DEBUG(fmt.Sprintf("Patch handler: got id param = %s", idStr))
+
+	taskID, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Invalid id: %d", taskID))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		log.ERROR("Wrong Content type in Patch Handler(db-service)")
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	var patch shared.TaskPatch
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patch); err != nil {
+		log.ERROR(fmt.Sprintf("Wrong format of JSON in Patch handler(db-service):%v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return
+	}
+
+	task, err := h.s.UpdateTask(ctx, taskID, patch)
+	if err != nil {
+		if h.writeConflict(w, r, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrTaskNotFound):
+			log.ERROR(fmt.Sprintf("Patch handler: task %d not found", taskID))
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, "task not found")
+		case errors.Is(err, service.ErrInvalidInput):
+			log.ERROR(fmt.Sprintf("Patch handler: invalid input: %v", err))
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		default:
+			log.ERROR(fmt.Sprintf("Patch handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+	log.INFO("Patch handler executed successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", shared.ETag(task.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(task)
+}
+
+func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	idStr := vars["id"]
-	h.log.DEBUG(fmt.Sprintf("Delete handler: got id param = %s", idStr))
+	log.DEBUG(fmt.Sprintf("Put handler: got id param = %s", idStr))
 
 	taskID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.log.ERROR(fmt.Sprintf("Invalid id: %d", taskID))
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		log.ERROR(fmt.Sprintf("Invalid id: %d", taskID))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
 		return
 	}
 
-	err = h.s.ModifyTask(ctx, taskID, "updateStatus")
+	expectedVersion, err := shared.ParseETag(r.Header.Get("If-Match"))
 	if err != nil {
+		log.ERROR(fmt.Sprintf("Put handler: %v", err))
+		msg := "invalid If-Match header"
+		if errors.Is(err, shared.ErrWeakETag) {
+			msg = "If-Match requires a strong ETag"
+		}
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, msg)
+		return
+	}
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		log.ERROR("Wrong Content type in Put Handler(db-service)")
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	var Task shared.Task
+	if err := json.NewDecoder(r.Body).Decode(&Task); err != nil {
+		log.ERROR(fmt.Sprintf("Wrong format of JSON in Put handler(db-service):%v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return
+	}
+	Task.ID = taskID
+
+	task, err := h.s.ReplaceTask(ctx, Task, expectedVersion)
+	if err != nil {
+		if h.writeConflict(w, r, err) {
+			return
+		}
 		switch {
 		case errors.Is(err, service.ErrTaskNotFound):
-			h.log.ERROR(fmt.Sprintf("Delete handler: task %d not found", taskID))
-			http.Error(w, "task not found", http.StatusNotFound)
+			log.ERROR(fmt.Sprintf("Put handler: task %d not found", taskID))
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, "task not found")
+		case errors.Is(err, service.ErrPreconditionFailed):
+			log.ERROR(fmt.Sprintf("Put handler: stale If-Match for task %d", taskID))
+			w.Header().Set("ETag", shared.ETag(task.Version))
+			shared.WriteError(w, r, http.StatusPreconditionFailed, shared.CodePreconditionFailed, "task was modified by another request")
+		case errors.Is(err, service.ErrInvalidInput):
+			log.ERROR(fmt.Sprintf("Put handler: invalid input: %v", err))
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
 		default:
-			h.log.ERROR(fmt.Sprintf("Patch handler: internal error: %v", err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			log.ERROR(fmt.Sprintf("Put handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
 		}
 		return
 	}
-	h.log.INFO("Patch handler executed successfully")
+	log.INFO("Put handler executed successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", shared.ETag(task.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(task)
+}
 
-	w.WriteHeader(http.StatusNoContent)
+// isJSONContentType принимает application/json и application/merge-patch+json.
+func isJSONContentType(ct string) bool {
+	return strings.HasPrefix(ct, "application/json") || strings.HasPrefix(ct, "application/merge-patch+json")
 }
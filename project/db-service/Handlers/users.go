@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	logger "myproject/project/Logger"
+	"myproject/project/db-service/database_connect/service"
+	"myproject/project/shared"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type UserHandler struct {
+	s   *service.UserService
+	log *logger.Logger
+}
+
+func NewUserHandler(s *service.UserService, log *logger.Logger) *UserHandler {
+	return &UserHandler{s, log}
+}
+
+func (h *UserHandler) decodeCredentials(w http.ResponseWriter, r *http.Request, op string) (shared.Credentials, bool) {
+	log := h.log.With(r.Context())
+	var creds shared.Credentials
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		log.ERROR(fmt.Sprintf("Wrong Content type in %s Handler(db-service)", op))
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return creds, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		log.ERROR(fmt.Sprintf("Wrong format of JSON in %s handler(db-service):%v", op, err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return creds, false
+	}
+	return creds, true
+}
+
+func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	creds, ok := h.decodeCredentials(w, r, "Register")
+	if !ok {
+		return
+	}
+
+	user, err := h.s.Register(r.Context(), creds)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		case errors.Is(err, service.ErrUserExists):
+			shared.WriteError(w, r, http.StatusConflict, shared.CodeConflict, err.Error())
+		default:
+			log.ERROR(fmt.Sprintf("Register handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+	log.INFO(fmt.Sprintf("Register handler: user created, ID=%d", user.ID))
+}
+
+func (h *UserHandler) Authenticate(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	creds, ok := h.decodeCredentials(w, r, "Authenticate")
+	if !ok {
+		return
+	}
+
+	user, err := h.s.Authenticate(r.Context(), creds)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, err.Error())
+		default:
+			log.ERROR(fmt.Sprintf("Authenticate handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+	log.INFO(fmt.Sprintf("Authenticate handler: user %d authenticated", user.ID))
+}
+
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	users, err := h.s.ListUsers(r.Context())
+	if err != nil {
+		log.ERROR(fmt.Sprintf("List users handler: internal error: %v", err))
+		shared.WriteInternalError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+	log.INFO(fmt.Sprintf("List users handler executed successfully, count=%d", len(users)))
+}
+
+func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Get user handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+
+	user, err := h.s.GetUser(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, err.Error())
+			return
+		}
+		log.ERROR(fmt.Sprintf("Get user handler: internal error: %v", err))
+		shared.WriteInternalError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+	log.DEBUG("Get user handler executed successfully", "user_id", user.ID)
+}
+
+func (h *UserHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With(r.Context())
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.ERROR(fmt.Sprintf("SetRole handler: invalid id: %v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid id")
+		return
+	}
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		log.ERROR("Wrong Content type in SetRole Handler(db-service)")
+		shared.WriteError(w, r, http.StatusUnsupportedMediaType, shared.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	var req shared.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ERROR(fmt.Sprintf("Wrong format of JSON in SetRole handler(db-service):%v", err))
+		shared.WriteError(w, r, http.StatusBadRequest, shared.CodeInvalidJSON, "invalid JSON body")
+		return
+	}
+
+	user, err := h.s.SetRole(r.Context(), userID, req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeValidation, err.Error())
+		case errors.Is(err, service.ErrUserNotFound):
+			shared.WriteError(w, r, http.StatusNotFound, shared.CodeNotFound, err.Error())
+		default:
+			log.ERROR(fmt.Sprintf("SetRole handler: internal error: %v", err))
+			shared.WriteInternalError(w, r)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+	log.INFO(fmt.Sprintf("SetRole handler: user %d is now %s", user.ID, user.Role))
+}
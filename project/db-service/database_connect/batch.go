@@ -0,0 +1,160 @@
+package databaseconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myproject/project/shared"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BatchItem - результат одного элемента пакетной операции.
+// Err != nil означает, что элемент не применён.
+type BatchItem struct {
+	Task shared.Task
+	Err  error
+}
+
+// BatchError указывает, на каком элементе прервалась атомарная операция.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+type batchQuery struct {
+	sql  string
+	args []any
+}
+
+// runBatch выполняет запросы в одной транзакции. В атомарном режиме запросы
+// отправляются одним pgx.Batch и любая ошибка откатывает всю транзакцию.
+// В частичном режиме каждый запрос выполняется в своей точке сохранения,
+// поэтому ошибка одного элемента не затрагивает остальные.
+func (s *Storage) runBatch(ctx context.Context, queries []batchQuery, atomic bool) ([]BatchItem, error) {
+	log := s.log.With(ctx)
+	items := make([]BatchItem, len(queries))
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if atomic {
+			b := &pgx.Batch{}
+			for _, q := range queries {
+				b.Queue(q.sql, q.args...)
+			}
+			br := tx.SendBatch(ctx, b)
+			for i := range queries {
+				if err := scanTask(br.QueryRow(), &items[i].Task); err != nil {
+					br.Close()
+					return &BatchError{Index: i, Err: err}
+				}
+			}
+			return br.Close()
+		}
+
+		for i, q := range queries {
+			sp, err := tx.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			if err := scanTask(sp.QueryRow(ctx, q.sql, q.args...), &items[i].Task); err != nil {
+				items[i].Err = err
+				if rbErr := sp.Rollback(ctx); rbErr != nil {
+					return rbErr
+				}
+				continue
+			}
+			if err := sp.Commit(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.ERROR(fmt.Sprintf("batch of %d failed (atomic=%t): %v", len(queries), atomic, err))
+		return nil, err
+	}
+	log.DEBUG(fmt.Sprintf("batch of %d executed (atomic=%t)", len(queries), atomic))
+	return items, nil
+}
+
+// mapBatchErrors применяет mapErr к ошибке каждого элемента, в том числе
+// к элементу, на котором прервалась атомарная операция.
+func mapBatchErrors(items []BatchItem, err error, mapErr func(i int, err error) error) ([]BatchItem, error) {
+	if err != nil {
+		var be *BatchError
+		if errors.As(err, &be) {
+			be.Err = mapErr(be.Index, be.Err)
+		}
+		return nil, err
+	}
+	for i := range items {
+		if items[i].Err != nil {
+			items[i].Err = mapErr(i, items[i].Err)
+		}
+	}
+	return items, nil
+}
+
+func (s *Storage) AddTasks(ctx context.Context, tasks []shared.Task, atomic bool) ([]BatchItem, error) {
+	owner, err := ownerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]batchQuery, len(tasks))
+	for i, t := range tasks {
+		queries[i] = batchQuery{
+			sql:  `INSERT INTO tasks (title, description, status, owner_id) VALUES ($1, $2, $3, $4) RETURNING ` + taskColumns,
+			args: []any{t.Title, t.Description, t.Status, owner},
+		}
+	}
+	items, err := s.runBatch(ctx, queries, atomic)
+	return mapBatchErrors(items, err, func(i int, err error) error {
+		return s.conflict(ctx, err, owner, &tasks[i].Title, 0)
+	})
+}
+
+func (s *Storage) UpdateTasks(ctx context.Context, patches []shared.BatchPatch, atomic bool) ([]BatchItem, error) {
+	owner, all, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]batchQuery, len(patches))
+	for i, p := range patches {
+		sql, args, err := updateQuery(p.ID, owner, all, p.TaskPatch)
+		if err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+		queries[i] = batchQuery{sql: sql, args: args}
+	}
+	items, err := s.runBatch(ctx, queries, atomic)
+	return mapBatchErrors(items, err, func(i int, err error) error {
+		return s.conflict(ctx, err, owner, patches[i].Title, patches[i].ID)
+	})
+}
+
+func (s *Storage) DeleteTasks(ctx context.Context, ids []int, atomic bool) ([]BatchItem, error) {
+	owner, all, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]batchQuery, len(ids))
+	for i, id := range ids {
+		queries[i] = batchQuery{
+			sql: `UPDATE tasks SET deleted_at = now(), version = version + 1
+                  WHERE id = $1 AND (owner_id = $2 OR $3) AND deleted_at IS NULL RETURNING ` + taskColumns,
+			args: []any{id, owner, all},
+		}
+	}
+	return s.runBatch(ctx, queries, atomic)
+}
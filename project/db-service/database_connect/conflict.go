@@ -0,0 +1,57 @@
+package databaseconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrConflict = errors.New("task conflicts with an existing task")
+
+// uniqueOpenTitleIndex - индекс правила open_title_per_owner (необязательная миграция
+// optional/open_title_per_owner, есть только при включённом правиле): у владельца
+// не может быть двух открытых задач с одинаковым (без учёта регистра) названием.
+const uniqueOpenTitleIndex = "tasks_owner_open_title_uniq"
+
+// ConflictError - задача нарушает правило уникальности.
+// errors.Is(err, ErrConflict) для неё истинно.
+type ConflictError struct {
+	// TaskID - id существующей задачи, с которой возник конфликт (0, если не найдена)
+	TaskID int
+	Rule   string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("task conflicts with existing task %d: %s", e.TaskID, e.Rule)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// conflict переводит нарушение уникального индекса правила в ConflictError
+// с id существующей задачи. Остальные ошибки возвращаются без изменений.
+// Правило действует в пределах владельца: для существующей задачи taskID
+// это её владелец, для новой - owner.
+// title = nil означает, что название не менялось и берётся у задачи taskID.
+func (s *Storage) conflict(ctx context.Context, err error, owner int, title *string, taskID int) error {
+	log := s.log.With(ctx)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgUniqueViolation || pgErr.ConstraintName != uniqueOpenTitleIndex {
+		return err
+	}
+
+	conflictErr := &ConflictError{Rule: "an open task with this title already exists"}
+	query := `
+        SELECT id FROM tasks
+        WHERE owner_id = COALESCE((SELECT owner_id FROM tasks WHERE id = $3), $1) AND status = FALSE AND deleted_at IS NULL AND id <> $3
+          AND lower(title) = lower(COALESCE($2, (SELECT title FROM tasks WHERE id = $3)))
+        LIMIT 1`
+	if lookupErr := s.db.QueryRow(ctx, query, owner, title, taskID).Scan(&conflictErr.TaskID); lookupErr != nil {
+		log.ERROR(fmt.Sprintf("conflict lookup failed: %v", lookupErr))
+	}
+	log.ERROR(fmt.Sprintf("unique rule violated: %v", conflictErr))
+	return conflictErr
+}
@@ -2,24 +2,53 @@ package databaseconnect
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	logger "myproject/project/Logger"
 	"myproject/project/shared"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// taskColumns - порядок колонок, который ожидает scanTask.
+const taskColumns = `id, title, description, status, created_at, version, deleted_at, owner_id`
+
+var ErrVersionMismatch = errors.New("task version mismatch")
+var ErrNoOwner = errors.New("request has no authenticated owner")
+
 type Storage struct {
 	db  *pgxpool.Pool
 	log *logger.Logger
 }
 
-func NewPool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
-	pool, err := pgxpool.New(ctx, dsn)
-	if err != nil {
-		return nil, err
+// taskFields возвращает приёмники Scan в порядке taskColumns.
+func taskFields(t *shared.Task) []any {
+	return []any{&t.ID, &t.Title, &t.Description, &t.Status, &t.Created_at, &t.Version, &t.Deleted_at, &t.Owner_id}
+}
+
+func scanTask(row pgx.Row, t *shared.Task) error {
+	return row.Scan(taskFields(t)...)
+}
+
+// ownerFrom возвращает id пользователя, от имени которого выполняется запрос.
+// Он становится владельцем создаваемых задач.
+func ownerFrom(ctx context.Context) (int, error) {
+	id, ok := shared.UserIDFromContext(ctx)
+	if !ok {
+		return 0, ErrNoOwner
 	}
-	return pool, nil
+	return id, nil
+}
+
+// ownerScope возвращает владельца, которым ограничиваются все запросы к tasks.
+// all = true снимает ограничение: api-service передаёт его только для ролей
+// с правом auth.ActionAllOwners.
+func ownerScope(ctx context.Context) (owner int, all bool, err error) {
+	owner, err = ownerFrom(ctx)
+	return owner, shared.AllOwnersFromContext(ctx), err
 }
 
 func NewUserPool(pool *pgxpool.Pool, log *logger.Logger) *Storage {
@@ -27,106 +56,421 @@ func NewUserPool(pool *pgxpool.Pool, log *logger.Logger) *Storage {
 }
 
 func (s *Storage) AddTask(ctx context.Context, task shared.Task) (int, error) {
+	log := s.log.With(ctx)
 	var insertedID int
 
+	owner, err := ownerFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-        INSERT INTO tasks (title, description, status)
-        VALUES ($1, $2, $3)
+        INSERT INTO tasks (title, description, status, owner_id)
+        VALUES ($1, $2, $3, $4)
         RETURNING id
     `
-	err := s.db.QueryRow(ctx, query,
+	err = s.db.QueryRow(ctx, query,
 		task.Title,
 		task.Description,
 		task.Status,
+		owner,
 	).Scan(&insertedID)
 
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("failed to execute query AddTask: %v", err))
-		return 0, err
+		log.ERROR(fmt.Sprintf("failed to execute query AddTask: %v", err))
+		return 0, s.conflict(ctx, err, owner, &task.Title, 0)
 	}
-	s.log.DEBUG(fmt.Sprintf("AddTask executed successfully, ID: %d", insertedID))
+	log.DEBUG(fmt.Sprintf("AddTask executed successfully, ID: %d", insertedID))
 
 	return insertedID, nil
 }
 
 func (s *Storage) GetTask(ctx context.Context, id int) (shared.Task, error) {
+	log := s.log.With(ctx)
 
 	var Task shared.Task
 
-	query := `SELECT id, title, description, status, created_at FROM tasks WHERE id = $1`
+	owner, all, err := ownerScope(ctx)
+	if err != nil {
+		return Task, err
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1 AND (owner_id = $2 OR $3) AND deleted_at IS NULL`
 
-	err := s.db.QueryRow(ctx, query, id).Scan(
-		&Task.ID,
-		&Task.Title,
-		&Task.Description,
-		&Task.Status,
-		&Task.Created_at,
-	)
+	err = scanTask(s.db.QueryRow(ctx, query, id, owner, all), &Task)
 
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			s.log.ERROR(fmt.Sprintf("task with id %d not found", id))
-			return Task, fmt.Errorf("task with id %d not found", id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.ERROR(fmt.Sprintf("task with id %d not found", id))
+			return Task, fmt.Errorf("task with id %d not found: %w", id, err)
 		}
-		s.log.ERROR(fmt.Sprintf("GetTask failed: %v", err))
+		log.ERROR(fmt.Sprintf("GetTask failed: %v", err))
 		return Task, err
 	}
-	s.log.DEBUG("GetTask executed successfully!")
+	log.DEBUG("GetTask executed successfully!")
 
 	return Task, nil
 }
 
-func (s *Storage) GetAllTasks(ctx context.Context) ([]shared.Task, error) {
-	query := `SELECT id, title, description, status, created_at FROM tasks ORDER BY created_at DESC`
+func (s *Storage) GetAllTasks(ctx context.Context, filter shared.TaskFilter) (shared.TaskPage, error) {
+	log := s.log.With(ctx)
+	page := shared.TaskPage{Tasks: []shared.Task{}}
 
-	rows, err := s.db.Query(ctx, query)
+	owner, all, err := ownerScope(ctx)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("GetAllTasks failed: %v", err))
-		return nil, err
+		return page, err
+	}
+
+	var conds []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !all {
+		conds = append(conds, "owner_id = "+arg(owner))
+	}
+	if filter.OwnerID > 0 {
+		conds = append(conds, "owner_id = "+arg(filter.OwnerID))
+	}
+	if filter.Trashed {
+		conds = append(conds, "deleted_at IS NOT NULL")
+	} else {
+		conds = append(conds, "deleted_at IS NULL")
+	}
+	if filter.Status != nil {
+		conds = append(conds, "status = "+arg(*filter.Status))
+	}
+	if filter.CreatedAfter != nil {
+		conds = append(conds, "created_at > "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+arg(*filter.CreatedBefore))
+	}
+
+	countQuery := `SELECT count(*) FROM tasks` + whereClause(conds)
+	if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&page.Total); err != nil {
+		log.ERROR(fmt.Sprintf("GetAllTasks count failed: %v", err))
+		return page, err
+	}
+
+	field, desc := filter.SortField()
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := shared.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return page, err
+		}
+		cond, err := cursorCondition(field, cmp, cursor, arg)
+		if err != nil {
+			return page, err
+		}
+		conds = append(conds, cond)
 	}
-	defer rows.Close()
 
-	tasks := []shared.Task{}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = shared.DefaultPageLimit
+	}
+	// Запрашиваем на одну строку больше, чтобы понять, есть ли следующая страница
+	query := `SELECT ` + taskColumns + ` FROM tasks` + whereClause(conds) +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", field, dir, dir, arg(limit+1))
+	if filter.Offset > 0 && filter.Cursor == "" {
+		query += " OFFSET " + arg(filter.Offset)
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("GetAllTasks failed: %v", err))
+		return page, err
+	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var t shared.Task
-		if err := rows.Scan(&t.ID, &t.Title, &t.Description, &t.Status, &t.Created_at); err != nil {
-			s.log.ERROR(fmt.Sprintf("GetAllTasks scan failed:%v", err))
-			return nil, err
+		if err := scanTask(rows, &t); err != nil {
+			log.ERROR(fmt.Sprintf("GetAllTasks scan failed:%v", err))
+			return page, err
+		}
+		page.Tasks = append(page.Tasks, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.ERROR(fmt.Sprintf("GetAllTasks rows error: %v", err))
+		return page, err
+	}
+
+	if len(page.Tasks) > limit {
+		page.Tasks = page.Tasks[:limit]
+		page.NextCursor = shared.EncodeCursor(cursorFor(filter.Sort, field, page.Tasks[limit-1]))
+	}
+	log.INFO(fmt.Sprintf("GetAllTasks executed successfully, count=%d, total=%d", len(page.Tasks), page.Total))
+	log.DEBUG("GetAllTasks query executed")
+
+	return page, nil
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func cursorCondition(field, cmp string, c shared.Cursor, arg func(any) string) (string, error) {
+	switch field {
+	case "id":
+		return fmt.Sprintf("id %s %s", cmp, arg(c.ID)), nil
+	case "created_at":
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return "", fmt.Errorf("%w: malformed cursor", shared.ErrInvalidFilter)
 		}
-		tasks = append(tasks, t)
+		return fmt.Sprintf("(created_at, id) %s (%s, %s)", cmp, arg(t), arg(c.ID)), nil
+	case "title":
+		return fmt.Sprintf("(title, id) %s (%s, %s)", cmp, arg(c.Value), arg(c.ID)), nil
+	}
+	return "", fmt.Errorf("%w: unsupported sort %q", shared.ErrInvalidFilter, field)
+}
+
+func cursorFor(sort, field string, t shared.Task) shared.Cursor {
+	if sort == "" {
+		sort = shared.DefaultSort
+	}
+	c := shared.Cursor{Sort: sort, ID: t.ID}
+	switch field {
+	case "created_at":
+		c.Value = t.Created_at.Format(time.RFC3339Nano)
+	case "title":
+		c.Value = t.Title
+	}
+	return c
+}
+
+// SearchTasks ищет по title и description через tsvector-колонку search.
+// Результаты упорядочены по ts_rank, совпадения в snippet выделены <b></b>.
+func (s *Storage) SearchTasks(ctx context.Context, q shared.SearchQuery) ([]shared.SearchResult, error) {
+	log := s.log.With(ctx)
+	owner, all, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	query := `
+        SELECT ` + taskColumns + `,
+               ts_rank(search, q) AS rank,
+               ts_headline('simple', title || ' ' || description, q,
+                           'StartSel=<b>, StopSel=</b>, MaxFragments=2, MaxWords=20, MinWords=5') AS snippet
+        FROM tasks, websearch_to_tsquery('simple', $1) AS q
+        WHERE (owner_id = $4 OR $5) AND deleted_at IS NULL AND search @@ q
+        ORDER BY rank DESC, id DESC
+        LIMIT $2 OFFSET $3`
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = shared.DefaultPageLimit
+	}
+	rows, err := s.db.Query(ctx, query, q.Q, limit, q.Offset, owner, all)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("SearchTasks failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []shared.SearchResult{}
+	for rows.Next() {
+		var r shared.SearchResult
+		if err := rows.Scan(append(taskFields(&r.Task), &r.Rank, &r.Snippet)...); err != nil {
+			log.ERROR(fmt.Sprintf("SearchTasks scan failed:%v", err))
+			return nil, err
+		}
+		results = append(results, r)
+	}
 	if err = rows.Err(); err != nil {
-		s.log.ERROR(fmt.Sprintf("GetAllTasks rows error: %v", err))
+		log.ERROR(fmt.Sprintf("SearchTasks rows error: %v", err))
 		return nil, err
 	}
-	s.log.INFO(fmt.Sprintf("GetAllTasks executed successfully, count=%d", len(tasks)))
-	s.log.DEBUG("GetAllTasks query executed")
+	log.INFO(fmt.Sprintf("SearchTasks executed successfully, count=%d", len(results)))
+	return results, nil
+}
+
+// updateQuery строит UPDATE только для заданных полей патча.
+func updateQuery(taskID, ownerID int, allOwners bool, patch shared.TaskPatch) (string, []any, error) {
+	var sets []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if patch.Title != nil {
+		sets = append(sets, "title = "+arg(*patch.Title))
+	}
+	if patch.Description != nil {
+		sets = append(sets, "description = "+arg(*patch.Description))
+	}
+	if patch.Status != nil {
+		sets = append(sets, "status = "+arg(*patch.Status))
+	}
+	if len(sets) == 0 {
+		return "", nil, fmt.Errorf("UpdateTask: nothing to update")
+	}
+
+	sets = append(sets, "version = version + 1")
+
+	query := `UPDATE tasks SET ` + strings.Join(sets, ", ") +
+		` WHERE id = ` + arg(taskID) + ` AND (owner_id = ` + arg(ownerID) + ` OR ` + arg(allOwners) + `) AND deleted_at IS NULL` +
+		` RETURNING ` + taskColumns
+	return query, args, nil
+}
+
+func (s *Storage) UpdateTask(ctx context.Context, taskID int, patch shared.TaskPatch) (shared.Task, error) {
+	log := s.log.With(ctx)
+	var Task shared.Task
+
+	owner, all, err := ownerScope(ctx)
+	if err != nil {
+		return Task, err
+	}
+
+	query, args, err := updateQuery(taskID, owner, all, patch)
+	if err != nil {
+		return Task, err
+	}
+
+	err = scanTask(s.db.QueryRow(ctx, query, args...), &Task)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("UpdateTask failed for ID=%d: %v", taskID, err))
+		return Task, s.conflict(ctx, err, owner, patch.Title, taskID)
+	}
+	log.INFO(fmt.Sprintf("Task updated successfully: ID=%d", taskID))
+	log.DEBUG(fmt.Sprintf("UpdateTask query executed for ID=%d", taskID))
+	return Task, nil
+}
+
+// ReplaceTask полностью заменяет задачу. Если expectedVersion > 0, замена
+// выполняется только при совпадении версии, иначе возвращается ErrVersionMismatch.
+func (s *Storage) ReplaceTask(ctx context.Context, task shared.Task, expectedVersion int) (shared.Task, error) {
+	log := s.log.With(ctx)
+	var Task shared.Task
+
+	owner, all, err := ownerScope(ctx)
+	if err != nil {
+		return Task, err
+	}
+
+	query := `
+        UPDATE tasks SET title = $2, description = $3, status = $4, version = version + 1
+        WHERE id = $1 AND (owner_id = $6 OR $7) AND deleted_at IS NULL AND ($5 = 0 OR version = $5)
+        RETURNING ` + taskColumns
+
+	err = scanTask(s.db.QueryRow(ctx, query,
+		task.ID,
+		task.Title,
+		task.Description,
+		task.Status,
+		expectedVersion,
+		owner,
+		all,
+	), &Task)
+	if err == nil {
+		log.INFO(fmt.Sprintf("Task replaced successfully: ID=%d, version=%d", Task.ID, Task.Version))
+		return Task, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) || expectedVersion == 0 {
+		log.ERROR(fmt.Sprintf("ReplaceTask failed for ID=%d: %v", task.ID, err))
+		return Task, s.conflict(ctx, err, owner, &task.Title, task.ID)
+	}
 
-	return tasks, nil
+	// Строка не обновилась: либо задачи нет, либо версия устарела
+	current, err := s.GetTask(ctx, task.ID)
+	if err != nil {
+		return Task, err
+	}
+	log.ERROR(fmt.Sprintf("ReplaceTask version mismatch for ID=%d: expected=%d, current=%d", task.ID, expectedVersion, current.Version))
+	return current, ErrVersionMismatch
 }
 
-func (s *Storage) UpdateTaskStatus(ctx context.Context, taskID int) (int64, error) {
-	query := `UPDATE tasks SET status=true WHERE id=$1`
-	cmdTag, err := s.db.Exec(ctx, query, taskID)
+// DeleteTask помещает задачу в корзину (soft delete).
+func (s *Storage) DeleteTask(ctx context.Context, taskID int) (int64, error) {
+	log := s.log.With(ctx)
+	owner, all, err := ownerScope(ctx)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("UpdateTaskStatus failed for ID=%d: %v", taskID, err))
 		return 0, err
 	}
-	s.log.INFO(fmt.Sprintf("Task status updated successfully: ID=%d", taskID))
-	s.log.DEBUG(fmt.Sprintf("UpdateTaskStatus query executed for ID=%d", taskID))
+
+	query := `UPDATE tasks SET deleted_at = now(), version = version + 1 WHERE id=$1 AND (owner_id=$2 OR $3) AND deleted_at IS NULL`
+	cmdTag, err := s.db.Exec(ctx, query, taskID, owner, all)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("DeleteTask failed for ID=%d: %v", taskID, err))
+		return 0, err
+	}
+	log.INFO(fmt.Sprintf("Task moved to trash: ID=%d", taskID))
+	log.DEBUG(fmt.Sprintf("DeleteTask query executed for ID=%d", taskID))
 	return cmdTag.RowsAffected(), nil
 }
 
-func (s *Storage) DeleteTask(ctx context.Context, taskID int) (int64, error) {
-	query := `DELETE FROM tasks WHERE id=$1`
-	cmdTag, err := s.db.Exec(ctx, query, taskID)
+// PurgeTask удаляет задачу безвозвратно, в том числе из корзины.
+func (s *Storage) PurgeTask(ctx context.Context, taskID int) (int64, error) {
+	log := s.log.With(ctx)
+	owner, all, err := ownerScope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `DELETE FROM tasks WHERE id=$1 AND (owner_id=$2 OR $3)`
+	cmdTag, err := s.db.Exec(ctx, query, taskID, owner, all)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("PurgeTask failed for ID=%d: %v", taskID, err))
+		return 0, err
+	}
+	log.INFO(fmt.Sprintf("Task purged successfully: ID=%d", taskID))
+	log.DEBUG(fmt.Sprintf("PurgeTask query executed for ID=%d", taskID))
+	return cmdTag.RowsAffected(), nil
+}
+
+// RestoreTask возвращает задачу из корзины.
+func (s *Storage) RestoreTask(ctx context.Context, taskID int) (shared.Task, error) {
+	log := s.log.With(ctx)
+	var Task shared.Task
+
+	owner, all, err := ownerScope(ctx)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("DeleteTask failed for ID=%d: %v", taskID, err))
+		return Task, err
+	}
+
+	query := `UPDATE tasks SET deleted_at = NULL, version = version + 1
+        WHERE id = $1 AND (owner_id = $2 OR $3) AND deleted_at IS NOT NULL
+        RETURNING ` + taskColumns
+
+	err = scanTask(s.db.QueryRow(ctx, query, taskID, owner, all), &Task)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("RestoreTask failed for ID=%d: %v", taskID, err))
+		return Task, s.conflict(ctx, err, owner, nil, taskID)
+	}
+	log.INFO(fmt.Sprintf("Task restored successfully: ID=%d", taskID))
+	return Task, nil
+}
+
+// PurgeDeletedBefore безвозвратно удаляет задачи, попавшие в корзину раньше before.
+func (s *Storage) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	log := s.log.With(ctx)
+	query := `DELETE FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	cmdTag, err := s.db.Exec(ctx, query, before)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("PurgeDeletedBefore failed: %v", err))
 		return 0, err
 	}
-	s.log.INFO(fmt.Sprintf("Task deleted successfully: ID=%d", taskID))
-	s.log.DEBUG(fmt.Sprintf("DeleteTask query executed for ID=%d", taskID))
+	log.DEBUG(fmt.Sprintf("PurgeDeletedBefore removed %d task(s)", cmdTag.RowsAffected()))
 	return cmdTag.RowsAffected(), nil
 }
+
+// Stat возвращает статистику пула соединений (для метрик).
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.db.Stat()
+}
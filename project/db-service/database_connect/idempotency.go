@@ -0,0 +1,81 @@
+package databaseconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myproject/project/shared"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotencyKeyTTL - сколько хранится ключ идемпотентности. После этого
+// ключ можно использовать заново.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key already used with a different request")
+
+// AddTaskIdempotent создаёт задачу, запоминая ключ и хэш запроса в той же транзакции.
+// Если ключ уже использовался с тем же хэшем, задача не создаётся, а возвращается
+// id, созданный первым запросом, и replayed = true. Другой хэш - ErrIdempotencyKeyReused.
+// Конкурентный запрос с тем же ключом ждёт завершения первой транзакции.
+func (s *Storage) AddTaskIdempotent(ctx context.Context, key, requestHash string, task shared.Task) (id int, replayed bool, err error) {
+	log := s.log.With(ctx)
+	owner, err := ownerFrom(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			`DELETE FROM idempotency_keys WHERE owner_id = $1 AND created_at < $2`,
+			owner, time.Now().Add(-IdempotencyKeyTTL))
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+            INSERT INTO idempotency_keys (owner_id, key, request_hash)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (owner_id, key) DO NOTHING
+        `, owner, key, requestHash)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			var storedHash string
+			err := tx.QueryRow(ctx,
+				`SELECT request_hash, task_id FROM idempotency_keys WHERE owner_id = $1 AND key = $2`,
+				owner, key).Scan(&storedHash, &id)
+			if err != nil {
+				return err
+			}
+			if storedHash != requestHash {
+				return ErrIdempotencyKeyReused
+			}
+			replayed = true
+			return nil
+		}
+
+		err = tx.QueryRow(ctx, `
+            INSERT INTO tasks (title, description, status, owner_id)
+            VALUES ($1, $2, $3, $4)
+            RETURNING id
+        `, task.Title, task.Description, task.Status, owner).Scan(&id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE idempotency_keys SET task_id = $3 WHERE owner_id = $1 AND key = $2`,
+			owner, key, id)
+		return err
+	})
+	if err != nil {
+		log.ERROR(fmt.Sprintf("AddTaskIdempotent failed, key=%q: %v", key, err))
+		return 0, false, s.conflict(ctx, err, owner, &task.Title, 0)
+	}
+	log.DEBUG(fmt.Sprintf("AddTaskIdempotent executed successfully, ID: %d, replayed: %t", id, replayed))
+	return id, replayed, nil
+}
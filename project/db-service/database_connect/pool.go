@@ -0,0 +1,93 @@
+package databaseconnect
+
+import (
+	"context"
+	"fmt"
+	logger "myproject/project/Logger"
+	"myproject/project/shared"
+	"myproject/project/tracing"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewPool создаёт пул и проверяет подключение: пока БД недоступна, Ping
+// повторяется с экспоненциальной задержкой, но не дольше cfg.ConnectTimeout.
+// Так неверный DSN или недоступная БД обнаруживаются при запуске, а не на первом запросе.
+func NewPool(ctx context.Context, dsn string, cfg shared.PoolConfig, log *logger.Logger) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	// Параметры сессии передаются при подключении, отдельный SET не нужен
+	params := poolCfg.ConnConfig.RuntimeParams
+	if cfg.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+	if cfg.ApplicationName != "" {
+		params["application_name"] = cfg.ApplicationName
+	}
+	if cfg.SearchPath != "" {
+		params["search_path"] = cfg.SearchPath
+	}
+	// Каждый запрос к БД - дочерний спан трассы входящего запроса
+	poolCfg.ConnConfig.Tracer = tracing.PgxTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ping(ctx, pool, cfg.ConnectTimeout, log); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+const (
+	pingBaseDelay = 100 * time.Millisecond
+	pingMaxDelay  = 5 * time.Second
+)
+
+// ping ждёт ответа БД. timeout <= 0 - одна попытка без повторов.
+func ping(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration, log *logger.Logger) error {
+	if timeout <= 0 {
+		return pool.Ping(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := pingBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := pool.Ping(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.INFO(fmt.Sprintf("Database reachable after %d attempts", attempt))
+			}
+			return nil
+		}
+		log.INFO(fmt.Sprintf("Database ping attempt %d failed: %v, retrying in %v", attempt, err, delay))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable within %s: %w", timeout, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, pingMaxDelay)
+	}
+}
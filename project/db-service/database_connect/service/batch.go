@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	databaseconnect "myproject/project/db-service/database_connect"
+	"myproject/project/shared"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Service) CreateTasks(ctx context.Context, tasks []shared.Task, mode string) ([]databaseconnect.BatchItem, error) {
+	return runBatch(ctx, s, "CreateTasks", tasks, mode, validateNewTask, s.repo.AddTasks)
+}
+
+func (s *Service) UpdateTasks(ctx context.Context, patches []shared.BatchPatch, mode string) ([]databaseconnect.BatchItem, error) {
+	return runBatch(ctx, s, "UpdateTasks", patches, mode, func(p shared.BatchPatch) error {
+		if p.ID <= 0 {
+			return fmt.Errorf("%w: id must be positive", ErrInvalidInput)
+		}
+		return validatePatch(p.TaskPatch)
+	}, s.repo.UpdateTasks)
+}
+
+func (s *Service) DeleteTasks(ctx context.Context, ids []int, mode string) ([]databaseconnect.BatchItem, error) {
+	return runBatch(ctx, s, "DeleteTasks", ids, mode, func(id int) error {
+		if id <= 0 {
+			return fmt.Errorf("%w: id must be positive", ErrInvalidInput)
+		}
+		return nil
+	}, s.repo.DeleteTasks)
+}
+
+func validateNewTask(task shared.Task) error {
+	if strings.TrimSpace(task.Title) == "" {
+		return fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
+	}
+	if task.Status {
+		return fmt.Errorf("%w: wrong status: task cannot be created with status = true", ErrInvalidInput)
+	}
+	return nil
+}
+
+func validatePatch(patch shared.TaskPatch) error {
+	if patch.IsEmpty() {
+		return fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+	if patch.Title != nil && strings.TrimSpace(*patch.Title) == "" {
+		return fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
+	}
+	return nil
+}
+
+// batchItemError переводит ошибки репозитория в ошибки сервиса.
+func batchItemError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTaskNotFound
+	}
+	return err
+}
+
+// runBatch проверяет элементы и передаёт их в репозиторий. В атомарном режиме
+// первая же ошибка отменяет весь пакет; в частичном невалидные элементы
+// отмечаются ошибкой, а остальные применяются.
+func runBatch[T any](
+	ctx context.Context,
+	s *Service,
+	op string,
+	items []T,
+	mode string,
+	validate func(T) error,
+	apply func(context.Context, []T, bool) ([]databaseconnect.BatchItem, error),
+) ([]databaseconnect.BatchItem, error) {
+	log := s.log.With(ctx)
+	if len(items) == 0 {
+		log.ERROR(fmt.Sprintf("%s validation failed: empty batch | %v", op, ErrInvalidInput))
+		return nil, fmt.Errorf("%w: batch is empty", ErrInvalidInput)
+	}
+	if len(items) > shared.MaxBatchSize {
+		log.ERROR(fmt.Sprintf("%s validation failed: %d items | %v", op, len(items), ErrInvalidInput))
+		return nil, fmt.Errorf("%w: batch cannot contain more than %d items", ErrInvalidInput, shared.MaxBatchSize)
+	}
+	atomic := mode != shared.BatchPartial
+
+	results := make([]databaseconnect.BatchItem, len(items))
+	valid := make([]T, 0, len(items))
+	index := make([]int, 0, len(items))
+	for i, item := range items {
+		if err := validate(item); err != nil {
+			if atomic {
+				log.ERROR(fmt.Sprintf("%s validation failed: item %d: %v", op, i, err))
+				return nil, &databaseconnect.BatchError{Index: i, Err: err}
+			}
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, item)
+		index = append(index, i)
+	}
+
+	if len(valid) > 0 {
+		applied, err := apply(ctx, valid, atomic)
+		if err != nil {
+			var be *databaseconnect.BatchError
+			if errors.As(err, &be) {
+				be.Index = index[be.Index]
+				be.Err = batchItemError(be.Err)
+			}
+			log.ERROR(fmt.Sprintf("%s repo call failed: %v", op, err))
+			return nil, err
+		}
+		for j, item := range applied {
+			if item.Err != nil {
+				item.Err = batchItemError(item.Err)
+			}
+			results[index[j]] = item
+		}
+	}
+
+	log.INFO(fmt.Sprintf("%s executed: %d item(s), mode=%s", op, len(items), mode))
+	return results, nil
+}
@@ -2,19 +2,27 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	logger "myproject/project/Logger"
+	databaseconnect "myproject/project/db-service/database_connect"
 	"myproject/project/db-service/repository"
 	"myproject/project/shared"
 
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 var ErrTaskNotFound = errors.New("task not found")
 var ErrEmptySlice = errors.New("there is no tasks")
-var ErrTooFewTasks = errors.New("there are too few tasks")
 var ErrInvalidInput = errors.New("invalid input")
+var ErrPreconditionFailed = errors.New("task version mismatch")
+var ErrIdempotencyKeyReused = errors.New("idempotency key already used with a different request")
 
 type Service struct {
 	repo repository.TaskRepository
@@ -26,90 +34,245 @@ func NewService(r repository.TaskRepository, log *logger.Logger) *Service {
 }
 
 func (s *Service) CreateTask(ctx context.Context, task shared.Task) (int, error) {
+	log := s.log.With(ctx)
 	// Валидация входных данных
-	if strings.TrimSpace(task.Title) == "" {
-		s.log.ERROR(fmt.Sprintf("CreateTask validation failed: title is empty | %v", ErrInvalidInput))
-		return 0, fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
-	}
-	if task.Status {
-		s.log.ERROR(fmt.Sprintf("CreateTask validation failed: status=true | %v", ErrInvalidInput))
-		return 0, fmt.Errorf("%w: wrong status: task cannot be created with status = true", ErrInvalidInput)
+	if err := validateNewTask(task); err != nil {
+		log.ERROR(fmt.Sprintf("CreateTask validation failed: %v", err))
+		return 0, err
 	}
 
 	// Вызов репозитория
 	id, err := s.repo.AddTask(ctx, task)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("CreateTask repo.AddTask failed: %v", err))
+		log.ERROR(fmt.Sprintf("CreateTask repo.AddTask failed: %v", err))
 		return 0, err
 	}
 
 	// Логирование успешного результата
-	s.log.INFO(fmt.Sprintf("Task created successfully: ID=%d", id))
-	s.log.DEBUG(fmt.Sprintf("CreateTask details: %+v", task))
+	log.INFO(fmt.Sprintf("Task created successfully: ID=%d", id))
+	log.DEBUG("CreateTask details", "task_id", id, "owner_id", task.Owner_id, "status", task.Status)
 
 	return id, nil
 }
 
+// CreateTaskIdempotent создаёт задачу не более одного раза на ключ идемпотентности.
+// Повтор с тем же телом возвращает id исходной задачи и replayed = true.
+func (s *Service) CreateTaskIdempotent(ctx context.Context, key string, task shared.Task) (int, bool, error) {
+	log := s.log.With(ctx)
+	if len(key) == 0 || len(key) > shared.MaxIdempotencyKeyLen {
+		log.ERROR(fmt.Sprintf("CreateTaskIdempotent: invalid key length %d", len(key)))
+		return 0, false, fmt.Errorf("%w: idempotency key must be 1-%d characters", ErrInvalidInput, shared.MaxIdempotencyKeyLen)
+	}
+	if err := validateNewTask(task); err != nil {
+		log.ERROR(fmt.Sprintf("CreateTaskIdempotent validation failed: %v", err))
+		return 0, false, err
+	}
+
+	id, replayed, err := s.repo.AddTaskIdempotent(ctx, key, requestHash(task), task)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("CreateTaskIdempotent repo.AddTaskIdempotent failed: %v", err))
+		if errors.Is(err, databaseconnect.ErrIdempotencyKeyReused) {
+			return 0, false, ErrIdempotencyKeyReused
+		}
+		return 0, false, err
+	}
+
+	if replayed {
+		log.INFO(fmt.Sprintf("Task creation replayed for idempotency key %q: ID=%d", key, id))
+	} else {
+		log.INFO(fmt.Sprintf("Task created successfully: ID=%d, idempotency key %q", id, key))
+	}
+	return id, replayed, nil
+}
+
+// requestHash - хэш полей задачи, которые задаёт клиент при создании.
+func requestHash(task shared.Task) string {
+	body, _ := json.Marshal(struct {
+		Title       string
+		Description string
+		Status      bool
+	}{task.Title, task.Description, task.Status})
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
 func (s *Service) GetTask(ctx context.Context, taskID int) (shared.Task, error) {
+	log := s.log.With(ctx)
 	//Вызов репозитория
 	task, err := s.repo.GetTask(ctx, taskID)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("repo.GetTask failed: %v", err))
+		log.ERROR(fmt.Sprintf("repo.GetTask failed: %v", err))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return task, ErrTaskNotFound
+		}
 		return task, err
 	}
 	//Валидация данных
 	if strings.TrimSpace(task.Title) == "" {
-		s.log.ERROR(fmt.Sprintf("GetTask validation failed: title is empty|%v", ErrInvalidInput))
+		log.ERROR(fmt.Sprintf("GetTask validation failed: title is empty|%v", ErrInvalidInput))
 		return task, fmt.Errorf("task title is empty")
 	}
-	s.log.INFO("GetTask(db-service) executed successfully")
-	s.log.DEBUG("Success")
+	log.INFO("GetTask(db-service) executed successfully")
+	log.DEBUG("Success")
 	return task, nil
 }
-func (s *Service) GetAllTasks(ctx context.Context) ([]shared.Task, error) {
-	tasks, err := s.repo.GetAllTasks(ctx)
+func (s *Service) GetAllTasks(ctx context.Context, filter shared.TaskFilter) (shared.TaskPage, error) {
+	log := s.log.With(ctx)
+	page, err := s.repo.GetAllTasks(ctx, filter)
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("repo.GetAllTasks failed: %v", err))
+		log.ERROR(fmt.Sprintf("repo.GetAllTasks failed: %v", err))
+		if errors.Is(err, shared.ErrInvalidFilter) {
+			return page, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		return page, err
+	}
+
+	if page.Total == 0 {
+		log.ERROR(fmt.Sprintf("Empty slice error: %v", ErrEmptySlice))
+		return page, ErrEmptySlice
+	}
+	log.INFO("GetAllTask(db-service) executed successfully!")
+	log.DEBUG("Success")
+
+	return page, nil
+}
+
+func (s *Service) SearchTasks(ctx context.Context, q shared.SearchQuery) ([]shared.SearchResult, error) {
+	log := s.log.With(ctx)
+	// Валидация входных данных
+	if strings.TrimSpace(q.Q) == "" {
+		log.ERROR(fmt.Sprintf("SearchTasks validation failed: empty query | %v", ErrInvalidInput))
+		return nil, fmt.Errorf("%w: search query cannot be empty", ErrInvalidInput)
+	}
+
+	results, err := s.repo.SearchTasks(ctx, q)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("repo.SearchTasks failed: %v", err))
 		return nil, err
 	}
+	log.INFO(fmt.Sprintf("SearchTasks(db-service) executed successfully, count=%d", len(results)))
+	return results, nil
+}
+
+func (s *Service) UpdateTask(ctx context.Context, taskID int, patch shared.TaskPatch) (shared.Task, error) {
+	log := s.log.With(ctx)
+	// Валидация входных данных
+	if err := validatePatch(patch); err != nil {
+		log.ERROR(fmt.Sprintf("UpdateTask validation failed: %v", err))
+		return shared.Task{}, err
+	}
 
-	if len(tasks) == 0 {
-		s.log.ERROR(fmt.Sprintf("Empty slice error: %v", err))
-		return nil, ErrEmptySlice
+	// Вызов репозитория
+	task, err := s.repo.UpdateTask(ctx, taskID, patch)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.ERROR(fmt.Sprintf("UpdateTask failed(task not found): id=%d", taskID))
+			return task, ErrTaskNotFound
+		}
+		log.ERROR(fmt.Sprintf("UpdateTask repo.UpdateTask failed: %v", err))
+		return task, err
 	}
+	log.INFO(fmt.Sprintf("Task updated successfully: ID=%d", taskID))
+	log.DEBUG("UpdateTask details", "task_id", taskID, "title_set", patch.Title != nil, "description_set", patch.Description != nil, "status_set", patch.Status != nil)
+	return task, nil
+}
 
-	if len(tasks) == 1 {
-		s.log.ERROR(fmt.Sprintf("Not many tasks that were expected: %v", err))
-		return nil, ErrTooFewTasks
+// ReplaceTask полностью заменяет задачу. expectedVersion = 0 отключает проверку версии.
+func (s *Service) ReplaceTask(ctx context.Context, task shared.Task, expectedVersion int) (shared.Task, error) {
+	log := s.log.With(ctx)
+	// Валидация входных данных
+	if strings.TrimSpace(task.Title) == "" {
+		log.ERROR(fmt.Sprintf("ReplaceTask validation failed: title is empty | %v", ErrInvalidInput))
+		return shared.Task{}, fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
 	}
-	s.log.INFO("GetAllTask(db-service) executed successfully!")
-	s.log.DEBUG("Success")
 
-	return tasks, nil
+	// Вызов репозитория
+	updated, err := s.repo.ReplaceTask(ctx, task, expectedVersion)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			log.ERROR(fmt.Sprintf("ReplaceTask failed(task not found): id=%d", task.ID))
+			return updated, ErrTaskNotFound
+		case errors.Is(err, databaseconnect.ErrVersionMismatch):
+			log.ERROR(fmt.Sprintf("ReplaceTask precondition failed: id=%d, current version=%d", task.ID, updated.Version))
+			return updated, ErrPreconditionFailed
+		}
+		log.ERROR(fmt.Sprintf("ReplaceTask repo.ReplaceTask failed: %v", err))
+		return updated, err
+	}
+	log.INFO(fmt.Sprintf("Task replaced successfully: ID=%d, version=%d", updated.ID, updated.Version))
+	return updated, nil
 }
 
 func (s *Service) ModifyTask(ctx context.Context, taskID int, action string) error {
+	log := s.log.With(ctx)
 	var rowsAffected int64
 	var err error
 
 	switch action {
-	case "updateStatus":
-		rowsAffected, err = s.repo.UpdateTaskStatus(ctx, taskID)
 	case "delete":
 		rowsAffected, err = s.repo.DeleteTask(ctx, taskID)
+	case "purge":
+		rowsAffected, err = s.repo.PurgeTask(ctx, taskID)
 	default:
 		return fmt.Errorf("unknown action")
 	}
 
 	if err != nil {
-		s.log.ERROR(fmt.Sprintf("ModifyTask failed: %v", err))
+		log.ERROR(fmt.Sprintf("ModifyTask failed: %v", err))
 		return err
 	}
 	if rowsAffected == 0 {
-		s.log.ERROR(fmt.Sprintf("ModifyTask failed(task not found): %v", err))
+		log.ERROR(fmt.Sprintf("ModifyTask failed(task not found): %v", err))
 		return ErrTaskNotFound
 	}
-	s.log.INFO("ModifyTask(db-service) executed successfully")
-	s.log.DEBUG("Success")
+	log.INFO("ModifyTask(db-service) executed successfully")
+	log.DEBUG("Success")
 	return nil
 }
+
+func (s *Service) RestoreTask(ctx context.Context, taskID int) (shared.Task, error) {
+	log := s.log.With(ctx)
+	task, err := s.repo.RestoreTask(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.ERROR(fmt.Sprintf("RestoreTask failed(task not in trash): id=%d", taskID))
+			return task, ErrTaskNotFound
+		}
+		log.ERROR(fmt.Sprintf("RestoreTask repo.RestoreTask failed: %v", err))
+		return task, err
+	}
+	log.INFO(fmt.Sprintf("Task restored successfully: ID=%d", taskID))
+	return task, nil
+}
+
+// PurgeTrash безвозвратно удаляет задачи, пролежавшие в корзине дольше retention.
+func (s *Service) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	log := s.log.With(ctx)
+	n, err := s.repo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.ERROR(fmt.Sprintf("PurgeTrash failed: %v", err))
+		return 0, err
+	}
+	if n > 0 {
+		log.INFO(fmt.Sprintf("PurgeTrash removed %d task(s) older than %s", n, retention))
+	}
+	return n, nil
+}
+
+// RunTrashPurger периодически вызывает PurgeTrash до отмены ctx.
+func (s *Service) RunTrashPurger(ctx context.Context, retention, interval time.Duration) {
+	log := s.log.With(ctx)
+	log.INFO(fmt.Sprintf("Trash purger started: retention=%s, interval=%s", retention, interval))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.INFO("Trash purger stopped")
+			return
+		case <-ticker.C:
+			s.PurgeTrash(ctx, retention)
+		}
+	}
+}
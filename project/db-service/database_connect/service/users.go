@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	logger "myproject/project/Logger"
+	"myproject/project/auth"
+	databaseconnect "myproject/project/db-service/database_connect"
+	"myproject/project/db-service/repository"
+	"myproject/project/shared"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUserExists = errors.New("user already exists")
+var ErrInvalidCredentials = errors.New("invalid username or password")
+var ErrUserNotFound = errors.New("user not found")
+
+type UserService struct {
+	repo repository.UserRepository
+	log  *logger.Logger
+}
+
+func NewUserService(r repository.UserRepository, log *logger.Logger) *UserService {
+	return &UserService{r, log}
+}
+
+// Register создаёт пользователя с ролью editor. Администраторы создаются
+// только командой db-service user create -admin.
+func (s *UserService) Register(ctx context.Context, creds shared.Credentials) (shared.User, error) {
+	return s.Create(ctx, creds, auth.RoleEditor)
+}
+
+func (s *UserService) Create(ctx context.Context, creds shared.Credentials, role auth.Role) (shared.User, error) {
+	log := s.log.With(ctx)
+	// Валидация входных данных
+	if err := auth.ValidateCredentials(creds.Username, creds.Password); err != nil {
+		log.ERROR(fmt.Sprintf("Register validation failed: %v", err))
+		return shared.User{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	hash, err := auth.HashPassword(creds.Password)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("Register hash failed: %v", err))
+		return shared.User{}, err
+	}
+
+	user, err := s.repo.CreateUser(ctx, creds.Username, hash, string(role))
+	if err != nil {
+		if errors.Is(err, databaseconnect.ErrUserExists) {
+			return user, ErrUserExists
+		}
+		log.ERROR(fmt.Sprintf("Register repo.CreateUser failed: %v", err))
+		return user, err
+	}
+	log.INFO(fmt.Sprintf("User registered successfully: ID=%d, role=%s", user.ID, user.Role))
+	return user, nil
+}
+
+// Authenticate проверяет логин и пароль. Для несуществующего пользователя
+// и неверного пароля возвращается одна и та же ошибка.
+func (s *UserService) Authenticate(ctx context.Context, creds shared.Credentials) (shared.User, error) {
+	log := s.log.With(ctx)
+	user, hash, err := s.repo.GetUserByUsername(ctx, creds.Username)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.ERROR(fmt.Sprintf("Authenticate repo.GetUserByUsername failed: %v", err))
+		return shared.User{}, err
+	}
+
+	if err := auth.CheckPassword(hash, creds.Password); err != nil {
+		log.ERROR(fmt.Sprintf("Authenticate failed for username %q", creds.Username))
+		return shared.User{}, ErrInvalidCredentials
+	}
+	log.INFO(fmt.Sprintf("User authenticated successfully: ID=%d", user.ID))
+	return user, nil
+}
+
+func (s *UserService) GetUser(ctx context.Context, userID int) (shared.User, error) {
+	log := s.log.With(ctx)
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, ErrUserNotFound
+		}
+		log.ERROR(fmt.Sprintf("repo.GetUser failed: %v", err))
+		return user, err
+	}
+	return user, nil
+}
+
+func (s *UserService) ListUsers(ctx context.Context) ([]shared.User, error) {
+	log := s.log.With(ctx)
+	users, err := s.repo.ListUsers(ctx)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("repo.ListUsers failed: %v", err))
+		return nil, err
+	}
+	log.INFO(fmt.Sprintf("ListUsers executed successfully, count=%d", len(users)))
+	return users, nil
+}
+
+func (s *UserService) SetRole(ctx context.Context, userID int, role string) (shared.User, error) {
+	log := s.log.With(ctx)
+	// Валидация входных данных
+	if !auth.Role(role).Valid() {
+		log.ERROR(fmt.Sprintf("SetRole validation failed: role %q | %v", role, ErrInvalidInput))
+		return shared.User{}, fmt.Errorf("%w: role must be viewer, editor or admin", ErrInvalidInput)
+	}
+
+	user, err := s.repo.SetUserRole(ctx, userID, role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, ErrUserNotFound
+		}
+		log.ERROR(fmt.Sprintf("repo.SetUserRole failed: %v", err))
+		return user, err
+	}
+	log.INFO(fmt.Sprintf("SetRole executed successfully: ID=%d, role=%s", userID, role))
+	return user, nil
+}
@@ -0,0 +1,106 @@
+package databaseconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myproject/project/shared"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrUserExists = errors.New("user already exists")
+
+const userColumns = `id, username, role, created_at`
+
+func scanUser(row pgx.Row, u *shared.User) error {
+	return row.Scan(&u.ID, &u.Username, &u.Role, &u.Created_at)
+}
+
+// pgUniqueViolation - код ошибки PostgreSQL unique_violation.
+const pgUniqueViolation = "23505"
+
+func (s *Storage) CreateUser(ctx context.Context, username, passwordHash, role string) (shared.User, error) {
+	log := s.log.With(ctx)
+	var user shared.User
+
+	query := `INSERT INTO users (username, password_hash, role) VALUES ($1, $2, $3) RETURNING ` + userColumns
+	err := scanUser(s.db.QueryRow(ctx, query, username, passwordHash, role), &user)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			log.ERROR(fmt.Sprintf("CreateUser: username %q already taken", username))
+			return user, ErrUserExists
+		}
+		log.ERROR(fmt.Sprintf("failed to execute query CreateUser: %v", err))
+		return user, err
+	}
+	log.DEBUG(fmt.Sprintf("CreateUser executed successfully, ID: %d", user.ID))
+	return user, nil
+}
+
+// GetUserByUsername возвращает пользователя вместе с хешем пароля.
+func (s *Storage) GetUserByUsername(ctx context.Context, username string) (shared.User, string, error) {
+	log := s.log.With(ctx)
+	var user shared.User
+	var hash string
+
+	query := `SELECT ` + userColumns + `, password_hash FROM users WHERE username = $1`
+	err := s.db.QueryRow(ctx, query, username).Scan(&user.ID, &user.Username, &user.Role, &user.Created_at, &hash)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("GetUserByUsername failed: %v", err))
+		return user, "", err
+	}
+	return user, hash, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, userID int) (shared.User, error) {
+	log := s.log.With(ctx)
+	var user shared.User
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	if err := scanUser(s.db.QueryRow(ctx, query, userID), &user); err != nil {
+		log.ERROR(fmt.Sprintf("GetUser failed for ID=%d: %v", userID, err))
+		return user, err
+	}
+	return user, nil
+}
+
+func (s *Storage) ListUsers(ctx context.Context) ([]shared.User, error) {
+	log := s.log.With(ctx)
+	rows, err := s.db.Query(ctx, `SELECT `+userColumns+` FROM users ORDER BY id`)
+	if err != nil {
+		log.ERROR(fmt.Sprintf("ListUsers failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []shared.User{}
+	for rows.Next() {
+		var u shared.User
+		if err := scanUser(rows, &u); err != nil {
+			log.ERROR(fmt.Sprintf("ListUsers scan failed:%v", err))
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		log.ERROR(fmt.Sprintf("ListUsers rows error: %v", err))
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, userID int, role string) (shared.User, error) {
+	log := s.log.With(ctx)
+	var user shared.User
+
+	query := `UPDATE users SET role = $2 WHERE id = $1 RETURNING ` + userColumns
+	if err := scanUser(s.db.QueryRow(ctx, query, userID, role), &user); err != nil {
+		log.ERROR(fmt.Sprintf("SetUserRole failed for ID=%d: %v", userID, err))
+		return user, err
+	}
+	log.INFO(fmt.Sprintf("User role updated: ID=%d, role=%s", userID, role))
+	return user, nil
+}
@@ -0,0 +1,473 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	logger "myproject/project/Logger"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql sql/optional/*.sql
+var files embed.FS
+
+// pgUndefinedTable - код ошибки PostgreSQL "relation does not exist".
+const pgUndefinedTable = "42P01"
+
+// lockKey - ключ pg_advisory_lock, чтобы миграции не выполнялись
+// одновременно из нескольких экземпляров db-service.
+const lockKey int64 = 72_616_001
+
+// noTransaction - директива в первой строке скрипта: миграция выполняется
+// вне транзакции, например для CREATE INDEX CONCURRENTLY.
+const noTransaction = "-- migrate:no-transaction"
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// NoTransaction - скрипты выполняются по одному оператору вне транзакции.
+	// Каждый оператор должен заканчиваться ";" в конце строки.
+	NoTransaction bool
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Optional - необязательная миграция, Enabled - включена ли она в конфиге
+	Optional bool
+	Enabled  bool
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	log        *logger.Logger
+	migrations []Migration
+	optional   []Migration
+	// enabled - какие необязательные миграции должны быть применены
+	enabled map[string]bool
+}
+
+// NewMigrator создаёт мигратор. enabled перечисляет необязательные миграции
+// (sql/optional/NAME.up.sql), которые Up применяет, пока они включены,
+// и откатывает после выключения. Отсутствующие в enabled считаются выключенными.
+func NewMigrator(pool *pgxpool.Pool, log *logger.Logger, enabled map[string]bool) (*Migrator, error) {
+	list, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	optional, err := LoadOptional()
+	if err != nil {
+		return nil, err
+	}
+	for name := range enabled {
+		if !slices.ContainsFunc(optional, func(m Migration) bool { return m.Name == name }) {
+			return nil, fmt.Errorf("unknown optional migration %q", name)
+		}
+	}
+	return &Migrator{pool: pool, log: log, migrations: list, optional: optional, enabled: enabled}, nil
+}
+
+// Load читает встроенные файлы вида NNNN_name.up.sql / NNNN_name.down.sql.
+func Load() ([]Migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		num, title, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name prefix", name)
+		}
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, num)
+		}
+
+		data, err := files.ReadFile(path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, title)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up script", m.Version, m.Name)
+		}
+		m.NoTransaction = strings.HasPrefix(m.Up, noTransaction)
+		if m.Down != "" && strings.HasPrefix(m.Down, noTransaction) != m.NoTransaction {
+			return nil, fmt.Errorf("migration %d_%s: up and down scripts must both be %q or neither", m.Version, m.Name, noTransaction)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// LoadOptional читает встроенные необязательные миграции sql/optional/NAME.up.sql
+// и NAME.down.sql. Скрипт down обязателен: им миграция откатывается при выключении.
+func LoadOptional() ([]Migration, error) {
+	entries, err := files.ReadDir("sql/optional")
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		base, up := strings.CutSuffix(name, ".up.sql")
+		if !up {
+			var down bool
+			if base, down = strings.CutSuffix(name, ".down.sql"); !down {
+				return nil, fmt.Errorf("optional migration %s: expected .up.sql or .down.sql suffix", name)
+			}
+		}
+		data, err := files.ReadFile(path.Join("sql/optional", name))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byName[base]
+		if !ok {
+			m = &Migration{Name: base}
+			byName[base] = m
+		}
+		if up {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	list := make([]Migration, 0, len(byName))
+	for _, m := range byName {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("optional migration %s: both up and down scripts are required", m.Name)
+		}
+		m.NoTransaction = strings.HasPrefix(m.Up, noTransaction)
+		if strings.HasPrefix(m.Down, noTransaction) != m.NoTransaction {
+			return nil, fmt.Errorf("optional migration %s: up and down scripts must both be %q or neither", m.Name, noTransaction)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	log := m.log.With(ctx)
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			log.ERROR(fmt.Sprintf("migrations: failed to release lock: %v", err))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version    BIGINT PRIMARY KEY,
+            name       TEXT NOT NULL,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	if _, err := conn.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_optional_migrations (
+            name       TEXT PRIMARY KEY,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )`); err != nil {
+		return fmt.Errorf("create schema_optional_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// querier - *pgxpool.Conn или *pgxpool.Pool.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func applied(ctx context.Context, conn querier) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		result[version] = at
+	}
+	return result, rows.Err()
+}
+
+func appliedOptional(ctx context.Context, conn querier) (map[string]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT name, applied_at FROM schema_optional_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string]time.Time{}
+	for rows.Next() {
+		var name string
+		var at time.Time
+		if err := rows.Scan(&name, &at); err != nil {
+			return nil, err
+		}
+		result[name] = at
+	}
+	return result, rows.Err()
+}
+
+// run выполняет скрипт миграции и запрос record, отмечающий её в schema_migrations.
+// Обычно всё выполняется в одной транзакции. Для NoTransaction операторы
+// выполняются по одному: несколько операторов в одном запросе PostgreSQL
+// тоже выполняет как транзакцию. Если такой скрипт прервётся, миграция
+// не будет отмечена и при повторе выполнится заново, поэтому её операторы
+// должны быть повторяемыми (IF EXISTS / IF NOT EXISTS).
+func run(ctx context.Context, conn *pgxpool.Conn, mig Migration, script, record string, args ...any) error {
+	if !mig.NoTransaction {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, script); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, record, args...)
+			return err
+		})
+	}
+	for _, stmt := range statements(script) {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	_, err := conn.Exec(ctx, record, args...)
+	return err
+}
+
+// statements делит скрипт на операторы по ";" в конце строки, пропуская комментарии.
+func statements(script string) []string {
+	var list []string
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			list = append(list, cur.String())
+			cur.Reset()
+		}
+	}
+	if strings.TrimSpace(cur.String()) != "" {
+		list = append(list, cur.String())
+	}
+	return list
+}
+
+// Up применяет все ещё не применённые миграции и возвращает их количество.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	log := m.log.With(ctx)
+	count := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			err := run(ctx, conn, mig, mig.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			log.INFO(fmt.Sprintf("migrations: applied %d_%s", mig.Version, mig.Name))
+			count++
+		}
+
+		// Необязательные миграции приводятся к конфигу после всех нумерованных
+		doneOptional, err := appliedOptional(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.optional {
+			_, isApplied := doneOptional[mig.Name]
+			switch enabled := m.enabled[mig.Name]; {
+			case enabled && !isApplied:
+				err = run(ctx, conn, mig, mig.Up, `INSERT INTO schema_optional_migrations (name) VALUES ($1)`, mig.Name)
+				if err != nil {
+					return fmt.Errorf("optional migration %s up: %w", mig.Name, err)
+				}
+				log.INFO(fmt.Sprintf("migrations: applied optional %s", mig.Name))
+			case !enabled && isApplied:
+				err = run(ctx, conn, mig, mig.Down, `DELETE FROM schema_optional_migrations WHERE name = $1`, mig.Name)
+				if err != nil {
+					return fmt.Errorf("optional migration %s down: %w", mig.Name, err)
+				}
+				log.INFO(fmt.Sprintf("migrations: rolled back disabled optional %s", mig.Name))
+			default:
+				continue
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down откатывает последнюю применённую нумерованную миграцию.
+// Необязательные миграции откатываются выключением в конфиге и Up.
+func (m *Migrator) Down(ctx context.Context) error {
+	log := m.log.With(ctx)
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+			}
+			err := run(ctx, conn, mig, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			log.INFO(fmt.Sprintf("migrations: rolled back %d_%s", mig.Version, mig.Name))
+			return nil
+		}
+		log.INFO("migrations: nothing to roll back")
+		return nil
+	})
+}
+
+// Status возвращает состояние каждой известной миграции.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			st := Status{Version: mig.Version, Name: mig.Name}
+			if at, ok := done[mig.Version]; ok {
+				st.Applied = true
+				st.AppliedAt = &at
+			}
+			result = append(result, st)
+		}
+
+		doneOptional, err := appliedOptional(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.optional {
+			st := Status{Name: mig.Name, Optional: true, Enabled: m.enabled[mig.Name]}
+			if at, ok := doneOptional[mig.Name]; ok {
+				st.Applied = true
+				st.AppliedAt = &at
+			}
+			result = append(result, st)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Pending возвращает число известных, но не применённых миграций, включая
+// необязательные, состояние которых расходится с конфигом.
+// В отличие от Status не берёт advisory lock и не создаёт schema_migrations,
+// поэтому годится для проверки готовности.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	done, err := applied(ctx, m.pool)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUndefinedTable {
+		return len(m.migrations), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, mig := range m.migrations {
+		if _, ok := done[mig.Version]; !ok {
+			pending++
+		}
+	}
+
+	doneOptional, err := appliedOptional(ctx, m.pool)
+	if errors.As(err, &pgErr) && pgErr.Code == pgUndefinedTable {
+		doneOptional, err = nil, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	for _, mig := range m.optional {
+		if _, ok := doneOptional[mig.Name]; ok != m.enabled[mig.Name] {
+			pending++
+		}
+	}
+	return pending, nil
+}
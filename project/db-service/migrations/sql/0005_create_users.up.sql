@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            SERIAL PRIMARY KEY,
+    username      TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Задачи, созданные до появления пользователей, остаются без владельца.
+-- Их видят только роли с доступом ко всем владельцам (admin).
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS tasks_owner_created_at_idx ON tasks (owner_id, created_at, id);
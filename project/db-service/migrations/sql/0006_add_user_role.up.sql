@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'editor'
+    CHECK (role IN ('viewer', 'editor', 'admin'));
+
+-- Администратор назначается явно: db-service user create -admin или PUT /users/{id}/role.
@@ -0,0 +1,10 @@
+-- Ключи идемпотентности POST /tasks. Для ключа хранится хэш тела запроса
+-- и ответ - id созданной задачи, который возвращается при повторе.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    owner_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    key          TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    task_id      INTEGER,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (owner_id, key)
+);
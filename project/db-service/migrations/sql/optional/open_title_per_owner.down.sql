@@ -0,0 +1,2 @@
+-- migrate:no-transaction
+DROP INDEX CONCURRENTLY IF EXISTS tasks_owner_open_title_uniq;
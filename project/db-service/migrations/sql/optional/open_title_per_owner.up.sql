@@ -0,0 +1,10 @@
+-- migrate:no-transaction
+-- Правило open_title_per_owner: у владельца нет двух открытых задач с одинаковым
+-- (без учёта регистра) названием. Индекс строится CONCURRENTLY, не блокируя запись в tasks.
+-- Если нарушения уже есть, миграция завершится ошибкой. Найти их:
+--   SELECT owner_id, lower(title), array_agg(id) FROM tasks
+--   WHERE status = FALSE AND deleted_at IS NULL GROUP BY 1, 2 HAVING count(*) > 1;
+-- Прерванная сборка оставляет невалидный индекс, поэтому он сначала удаляется.
+DROP INDEX CONCURRENTLY IF EXISTS tasks_owner_open_title_uniq;
+CREATE UNIQUE INDEX CONCURRENTLY tasks_owner_open_title_uniq
+    ON tasks (owner_id, lower(title)) WHERE status = FALSE AND deleted_at IS NULL;
@@ -4,14 +4,24 @@ import (
 	"context"
 	databaseconnect "myproject/project/db-service/database_connect"
 	"myproject/project/shared"
+	"time"
 )
 
 type TaskRepository interface {
-	GetTask(ctx context.Context, id int) (shared.Task, error)        //
-	AddTask(ctx context.Context, task shared.Task) (int, error)      //
-	GetAllTasks(ctx context.Context) ([]shared.Task, error)          //
-	UpdateTaskStatus(ctx context.Context, taskID int) (int64, error) //
-	DeleteTask(ctx context.Context, taskID int) (int64, error)       //
+	GetTask(ctx context.Context, id int) (shared.Task, error)                                                       //
+	AddTask(ctx context.Context, task shared.Task) (int, error)                                                     //
+	AddTaskIdempotent(ctx context.Context, key, requestHash string, task shared.Task) (int, bool, error)            //
+	GetAllTasks(ctx context.Context, filter shared.TaskFilter) (shared.TaskPage, error)                             //
+	SearchTasks(ctx context.Context, q shared.SearchQuery) ([]shared.SearchResult, error)                           //
+	UpdateTask(ctx context.Context, taskID int, patch shared.TaskPatch) (shared.Task, error)                        //
+	ReplaceTask(ctx context.Context, task shared.Task, expectedVersion int) (shared.Task, error)                    //
+	DeleteTask(ctx context.Context, taskID int) (int64, error)                                                      //
+	PurgeTask(ctx context.Context, taskID int) (int64, error)                                                       //
+	RestoreTask(ctx context.Context, taskID int) (shared.Task, error)                                               //
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)                                        //
+	AddTasks(ctx context.Context, tasks []shared.Task, atomic bool) ([]databaseconnect.BatchItem, error)            //
+	UpdateTasks(ctx context.Context, patches []shared.BatchPatch, atomic bool) ([]databaseconnect.BatchItem, error) //
+	DeleteTasks(ctx context.Context, ids []int, atomic bool) ([]databaseconnect.BatchItem, error)                   //
 }
 
 func NewTaskRepository(s *databaseconnect.Storage) TaskRepository {
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	databaseconnect "myproject/project/db-service/database_connect"
+	"myproject/project/shared"
+)
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, username, passwordHash, role string) (shared.User, error) //
+	GetUser(ctx context.Context, userID int) (shared.User, error)                             //
+	ListUsers(ctx context.Context) ([]shared.User, error)                                     //
+	SetUserRole(ctx context.Context, userID int, role string) (shared.User, error)            //
+	GetUserByUsername(ctx context.Context, username string) (shared.User, string, error)      //
+}
+
+func NewUserRepository(s *databaseconnect.Storage) UserRepository {
+	return s
+}
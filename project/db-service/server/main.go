@@ -1,12 +1,29 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	logger "myproject/project/Logger"
+	loggerpkg "myproject/project/Logger"
+	"myproject/project/auth"
+	"myproject/project/config"
 	handlers "myproject/project/db-service/Handlers"
 	databaseconnect "myproject/project/db-service/database_connect"
 	"myproject/project/db-service/database_connect/service"
+	"myproject/project/db-service/migrations"
+	"myproject/project/health"
+	"myproject/project/httpserver"
+	"myproject/project/metrics"
+	"myproject/project/middleware"
+	"myproject/project/shared"
+	"myproject/project/tracing"
 
 	"github.com/gorilla/mux"
 
@@ -14,35 +31,267 @@ import (
 )
 
 func main() {
-	logger := logger.NewLogger()
-	ctx := context.Background()
-	url, erro := databaseconnect.LoadConfig("database.yml")
-	if erro != nil {
-		logger.Error.Fatalf("error to lodconfig: %v", erro)
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "db-service: %v\n", err)
+		os.Exit(1)
 	}
+}
+
+// run возвращает ошибку вместо os.Exit, чтобы отложенные закрытия пула,
+// трассировки и логов выполнились и при сбое.
+func run() (err error) {
+	// SIGINT/SIGTERM запускают остановку, повторный сигнал завершает процесс сразу
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	path := flag.String("config", "database.yml", "path to the YAML config")
+	flag.Parse()
+	url := config.DefaultDB()
+	if err := config.Load(*path, url); err != nil {
+		return err
+	}
+	logger, err := loggerpkg.New(url.Log, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	// Закрывается последним, после пула и трассировки
+	defer func() {
+		if err != nil {
+			logger.ERROR(err.Error())
+		}
+		logger.Close()
+	}()
+	logger.RotateOnSIGHUP(ctx)
+
+	shutdownTracing, err := tracing.Setup(ctx, "db-service", url.Tracing)
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	pool, err := databaseconnect.NewPool(ctx, url.DatabaseURL, url.Pool, logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to db: %w", err)
+	}
+	defer func() {
+		logger.INFO("Closing database pool")
+		pool.Close()
+	}()
 
-	pool, err := databaseconnect.NewPool(ctx, url.DatabaseURL)
+	migrator, err := migrations.NewMigrator(pool, logger, url.Uniqueness.Migrations())
 	if err != nil {
-		logger.Error.Fatalf("failed to connect to db: %v", err)
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	// db-service [-config path] migrate up|down|status
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(ctx, migrator, args[1:]); err != nil {
+			return fmt.Errorf("migrate failed: %w", err)
+		}
+		return nil
+	}
+
+	// db-service [-config path] user create [-admin] USERNAME
+	if args := flag.Args(); len(args) > 0 && args[0] == "user" {
+		users := service.NewUserService(databaseconnect.NewUserPool(pool, logger), logger)
+		if err := runUser(ctx, users, args[1:]); err != nil {
+			return fmt.Errorf("user command failed: %w", err)
+		}
+		return nil
+	}
+
+	if url.MigrateOnStart {
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		logger.Info.Printf("Migrations applied: %d", n)
 	}
-	defer pool.Close()
 
 	repo := databaseconnect.NewUserPool(pool, logger)
 	logger.Info.Println("Repository Created")
 	s := service.NewService(repo, logger)
 	logger.Info.Println("Service Created")
 	h := handlers.NewHandler(*s, *logger)
+	uh := handlers.NewUserHandler(service.NewUserService(repo, logger), logger)
 	logger.Info.Println("Handler Created")
 
+	if url.TrashRetention > 0 {
+		go s.RunTrashPurger(ctx, url.TrashRetention, url.TrashPurgeInterval)
+	}
+
+	signer, err := auth.NewSigner(url.ServiceAuth.Secret)
+	if err != nil {
+		return fmt.Errorf("invalid service_auth config: %w", err)
+	}
+
+	// SIGHUP или изменение файла применяют уровень логов и дедлайны,
+	// остальное требует перезапуска
+	reloader := config.NewReloader(*path, url, config.DefaultDB, logger)
+	reloader.OnChange(func(cfg *config.DB) {
+		if err := logger.SetLevel(cfg.Log.Level); err != nil {
+			logger.ERROR(fmt.Sprintf("config reload: %v", err))
+		}
+	})
+	reloader.Watch(ctx, config.WatchInterval)
+
+	reg := metrics.NewRegistry()
+	reg.MustRegister(metrics.NewPoolCollector(repo.Stat))
+
 	r := mux.NewRouter()
-	r.HandleFunc("/tasks", h.Post).Methods("POST")
-	r.HandleFunc("/tasks/{id}", h.GetTask).Methods("GET")
-	r.HandleFunc("/tasks", h.AllTasks).Methods("GET")
-	r.HandleFunc("/tasks/{id}", h.Patch).Methods("PATCH")
-	r.HandleFunc("/tasks/{id}", h.Delete).Methods("DELETE")
+	r.NotFoundHandler = shared.NotFoundHandler()
+	r.MethodNotAllowedHandler = shared.MethodNotAllowedHandler()
+	// X-Request-ID приходит от api-service и связывает логи обоих сервисов
+	r.Use(middleware.RequestID)
+	// Спан продолжает трассу api-service из заголовка traceparent
+	r.Use(middleware.Tracing)
+	if url.Log.AccessLog.Path != "" {
+		r.Use(middleware.AccessLog(logger.Access))
+	}
+	r.Use(middleware.Metrics(metrics.NewHTTP(reg, "db-service")))
+	// Принимаются только запросы, подписанные api-service
+	r.Use(middleware.VerifySignature(signer, url.ServiceAuth.Window, logger))
+	// Дедлайн передаётся в pgx через контекст запроса
+	r.Use(middleware.DeadlineFrom(func() shared.Timeouts { return reloader.Current().Timeouts }))
+	r.HandleFunc("/users", uh.Register).Methods("POST")
+	r.HandleFunc("/users/authenticate", uh.Authenticate).Methods("POST")
+	r.HandleFunc("/users", uh.List).Methods("GET")
+	r.HandleFunc("/users/{id:[0-9]+}", uh.Get).Methods("GET")
+	r.HandleFunc("/users/{id}/role", uh.SetRole).Methods("PUT")
+	// Конфиг содержит хост и пользователя базы, поэтому отдаётся только по подписи
+	r.Handle("/admin/config", reloader.Handler()).Methods("GET")
+
+	// Все операции с задачами выполняются от имени пользователя из X-User-ID
+	tasks := r.NewRoute().Subrouter()
+	tasks.Use(middleware.TrustedUser)
+	tasks.HandleFunc("/tasks/batch", h.BatchPost).Methods("POST")
+	tasks.HandleFunc("/tasks/batch", h.BatchPatch).Methods("PATCH")
+	tasks.HandleFunc("/tasks/batch", h.BatchDelete).Methods("DELETE")
+	tasks.HandleFunc("/tasks/search", h.Search).Methods("GET")
+	tasks.HandleFunc("/tasks/trash", h.Trash).Methods("GET")
+	tasks.HandleFunc("/tasks/{id}/restore", h.Restore).Methods("POST")
+	tasks.HandleFunc("/tasks", h.Post).Methods("POST")
+	tasks.HandleFunc("/tasks/{id}", h.GetTask).Methods("GET")
+	tasks.HandleFunc("/tasks", h.AllTasks).Methods("GET")
+	tasks.HandleFunc("/tasks/{id}", h.Patch).Methods("PATCH")
+	tasks.HandleFunc("/tasks/{id}", h.Put).Methods("PUT")
+	tasks.HandleFunc("/tasks/{id}", h.Delete).Methods("DELETE")
+
+	ready := health.New(health.DefaultTimeout).
+		Add("postgres", pool.Ping).
+		Add("migrations", func(ctx context.Context) error {
+			n, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("%d migration(s) pending", n)
+			}
+			return nil
+		})
 
-	logger.Info.Println("Server started at :8081")
-	if err := http.ListenAndServe(":8081", r); err != nil {
-		logger.Error.Fatalf("server failed: %v", err)
+	// /metrics и проверки обслуживаются вне r, чтобы Prometheus и
+	// оркестратору не нужна была подпись api-service
+	root := http.NewServeMux()
+	root.Handle("GET /metrics", metrics.Handler(reg))
+	root.Handle("GET /healthz", health.Liveness())
+	root.Handle("GET /readyz", ready.Readiness())
+	root.Handle("/", r)
+
+	srv := httpserver.New(url.Listen.Addr, root, url.Server)
+	listen := srv.ListenAndServe
+	if url.TLS.CertFile != "" {
+		srv.TLSConfig, err = auth.ServerTLSConfig(url.TLS.CertFile, url.TLS.KeyFile, url.TLS.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("invalid tls config: %w", err)
+		}
+		listen = func() error { return srv.ListenAndServeTLS("", "") }
+		logger.Info.Printf("Server started at %s (mTLS)", url.Listen.Addr)
+	} else {
+		logger.Info.Printf("Server started at %s", url.Listen.Addr)
+	}
+	// Пул закрывается только после того, как завершились все принятые запросы
+	return httpserver.Serve(ctx, srv, url.Server, logger, listen, ready.Drain)
+}
+
+func runMigrate(ctx context.Context, m *migrations.Migrator, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: db-service migrate up|down|status")
+	}
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		return m.Down(ctx)
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range list {
+			applied := "pending"
+			if st.Applied {
+				applied = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if !st.Optional {
+				fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, applied)
+				continue
+			}
+			switch {
+			case !st.Enabled && st.Applied:
+				applied += ", disabled: rolled back by migrate up"
+			case !st.Enabled:
+				applied = "disabled"
+			}
+			fmt.Printf("optional/%s\t%s\n", st.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up, down or status)", args[0])
+	}
+	return nil
+}
+
+// runUser создаёт пользователя из командной строки. Пароль берётся из
+// USER_PASSWORD или читается первой строкой stdin, чтобы не попасть в историю команд.
+func runUser(ctx context.Context, users *service.UserService, args []string) error {
+	const usage = "usage: db-service user create [-admin] USERNAME"
+	if len(args) == 0 || args[0] != "create" {
+		return errors.New(usage)
+	}
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	admin := fs.Bool("admin", false, "create the user with the admin role")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(usage)
+	}
+
+	password, ok := os.LookupEnv("USER_PASSWORD")
+	if !ok {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	role := auth.RoleEditor
+	if *admin {
+		role = auth.RoleAdmin
+	}
+	user, err := users.Create(ctx, shared.Credentials{Username: fs.Arg(0), Password: password}, role)
+	if err != nil {
+		return err
 	}
+	fmt.Printf("created user %d %q with role %s\n", user.ID, user.Username, user.Role)
+	return nil
 }
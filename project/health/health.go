@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout ограничивает все проверки одного запроса /readyz.
+const DefaultTimeout = 3 * time.Second
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check проверяет одну зависимость. nil - зависимость доступна.
+type Check func(ctx context.Context) error
+
+// CheckResult - результат проверки одной зависимости.
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Response - тело /healthz и /readyz.
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type named struct {
+	name  string
+	check Check
+}
+
+// Checker выполняет проверки зависимостей для /readyz.
+type Checker struct {
+	timeout  time.Duration
+	checks   []named
+	draining atomic.Bool
+}
+
+// New создаёт Checker. timeout <= 0 заменяется DefaultTimeout.
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку зависимости name. Вызывается до запуска сервера.
+func (c *Checker) Add(name string, check Check) *Checker {
+	c.checks = append(c.checks, named{name, check})
+	return c
+}
+
+// Drain переводит /readyz в fail до конца работы процесса. Вызывается
+// в начале остановки, чтобы на экземпляр перестали направлять запросы.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Run выполняет все проверки параллельно.
+func (c *Checker) Run(ctx context.Context) Response {
+	if c.draining.Load() {
+		return Response{Status: StatusFail, Checks: map[string]CheckResult{
+			"shutdown": {Status: StatusFail, Error: "server is shutting down"},
+		}}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp := Response{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, n := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			res := CheckResult{Status: StatusOK}
+			if err := n.check(ctx); err != nil {
+				res = CheckResult{Status: StatusFail, Error: err.Error()}
+			}
+			res.DurationMS = time.Since(start).Milliseconds()
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[n.name] = res
+			if res.Status != StatusOK {
+				resp.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return resp
+}
+
+// Liveness отвечает 200, пока процесс способен обслуживать HTTP.
+// Зависимости не проверяются, чтобы их сбой не приводил к перезапуску.
+func Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write(w, http.StatusOK, Response{Status: StatusOK})
+	})
+}
+
+// Readiness отвечает 200, если все проверки прошли, иначе 503.
+// В теле - результат по каждой зависимости.
+func (c *Checker) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := c.Run(r.Context())
+		status := http.StatusOK
+		if resp.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		write(w, status, resp)
+	})
+}
+
+func write(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,64 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	logger "myproject/project/Logger"
+	"myproject/project/shared"
+)
+
+// New создаёт http.Server с таймаутами из cfg.
+func New(addr string, h http.Handler, cfg shared.ServerConfig) *http.Server {
+	cfg = cfg.WithDefaults()
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// Serve запускает srv функцией listen (ListenAndServe или ListenAndServeTLS)
+// и ждёт отмены ctx. Затем останавливается по порядку: onDrain (перевод
+// /readyz в fail), пауза DrainDelay, закрытие слушателя и ожидание
+// обрабатываемых запросов не дольше ShutdownTimeout. Запросы, не успевшие
+// завершиться, обрываются. Возвращает ошибку запуска или остановки.
+func Serve(ctx context.Context, srv *http.Server, cfg shared.ServerConfig, log *logger.Logger, listen func() error, onDrain func()) error {
+	cfg = cfg.WithDefaults()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- listen()
+	}()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.INFO(fmt.Sprintf("Shutting down %s: draining for %s, grace period %s", srv.Addr, cfg.DrainDelay, cfg.ShutdownTimeout))
+	if onDrain != nil {
+		onDrain()
+	}
+	if cfg.DrainDelay > 0 {
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("graceful shutdown: %w", err)
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	log.INFO(fmt.Sprintf("Server %s stopped", srv.Addr))
+	return nil
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRegistry создаёт реестр с метриками рантайма Go и процесса.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// Handler отдаёт метрики реестра для GET /metrics.
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
+}
+
+// HTTP - метрики входящих запросов. Метка route - шаблон маршрута mux,
+// а не путь запроса, чтобы id задач не раздували число рядов.
+type HTTP struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+func NewHTTP(reg prometheus.Registerer, service string) *HTTP {
+	labels := prometheus.Labels{"service": service}
+	m := &HTTP{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "http_requests_total",
+			Help:        "Processed HTTP requests.",
+			ConstLabels: labels,
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "http_request_duration_seconds",
+			Help:        "HTTP request latency.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "http_requests_in_flight",
+			Help:        "HTTP requests currently being served.",
+			ConstLabels: labels,
+		}),
+	}
+	reg.MustRegister(m.requests, m.duration, m.inFlight)
+	return m
+}
+
+// Start отмечает начало запроса, возвращённая функция - его завершение.
+func (m *HTTP) Start() func(method, route string, status int) {
+	start := time.Now()
+	m.inFlight.Inc()
+	return func(method, route string, status int) {
+		m.inFlight.Dec()
+		code := strconv.Itoa(status)
+		m.requests.WithLabelValues(method, route, code).Inc()
+		m.duration.WithLabelValues(method, route, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Upstream - метрики исходящих запросов к другому сервису (по одной на попытку).
+type Upstream struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+func NewUpstream(reg prometheus.Registerer, upstream string) *Upstream {
+	labels := prometheus.Labels{"upstream": upstream}
+	m := &Upstream{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "upstream_request_duration_seconds",
+			Help:        "Latency of requests to an upstream service, per attempt.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "upstream_request_errors_total",
+			Help:        "Failed requests to an upstream service by reason.",
+			ConstLabels: labels,
+		}, []string{"method", "route", "reason"}),
+	}
+	reg.MustRegister(m.duration, m.errors)
+	return m
+}
+
+// Причины в upstream_request_errors_total.
+const (
+	ReasonNetwork     = "network"
+	ReasonStatus      = "status"
+	ReasonCanceled    = "canceled"
+	ReasonCircuitOpen = "circuit_open"
+)
+
+// Observe записывает попытку. status = 0, если ответа не было.
+func (m *Upstream) Observe(method, path string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	code := "none"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	m.duration.WithLabelValues(method, Route(path), code).Observe(d.Seconds())
+}
+
+func (m *Upstream) Error(method, path, reason string) {
+	if m == nil {
+		return
+	}
+	m.errors.WithLabelValues(method, Route(path), reason).Inc()
+}
+
+var numericSegment = regexp.MustCompile(`/\d+(/|$)`)
+
+// Route заменяет числовые сегменты пути на {id}: /tasks/5/restore -> /tasks/{id}/restore.
+func Route(path string) string {
+	// Соседние сегменты перекрываются, поэтому замена повторяется
+	for numericSegment.MatchString(path) {
+		path = numericSegment.ReplaceAllString(path, "/{id}$1")
+	}
+	return path
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector отдаёт статистику pgxpool на момент сбора метрик.
+type PoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquired, idle, total, max, constructing *prometheus.Desc
+	acquireCount, acquireSeconds             *prometheus.Desc
+	emptyAcquireCount, emptyAcquireSeconds   *prometheus.Desc
+	canceledAcquireCount                     *prometheus.Desc
+}
+
+func NewPoolCollector(stat func() *pgxpool.Stat) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+name, help, nil, nil)
+	}
+	return &PoolCollector{
+		stat:                 stat,
+		acquired:             desc("acquired_conns", "Connections currently checked out of the pool."),
+		idle:                 desc("idle_conns", "Idle connections in the pool."),
+		total:                desc("total_conns", "Total connections in the pool."),
+		max:                  desc("max_conns", "Maximum size of the pool."),
+		constructing:         desc("constructing_conns", "Connections being established."),
+		acquireCount:         desc("acquire_count_total", "Successful connection acquires."),
+		acquireSeconds:       desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquireCount:    desc("empty_acquire_count_total", "Acquires that had to wait because the pool was empty."),
+		emptyAcquireSeconds:  desc("empty_acquire_wait_seconds_total", "Total time spent waiting for a connection on an empty pool."),
+		canceledAcquireCount: desc("canceled_acquire_count_total", "Acquires canceled by their context."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(c.acquired, float64(s.AcquiredConns()))
+	gauge(c.idle, float64(s.IdleConns()))
+	gauge(c.total, float64(s.TotalConns()))
+	gauge(c.max, float64(s.MaxConns()))
+	gauge(c.constructing, float64(s.ConstructingConns()))
+	counter(c.acquireCount, float64(s.AcquireCount()))
+	counter(c.acquireSeconds, s.AcquireDuration().Seconds())
+	counter(c.emptyAcquireCount, float64(s.EmptyAcquireCount()))
+	counter(c.emptyAcquireSeconds, s.EmptyAcquireWaitTime().Seconds())
+	counter(c.canceledAcquireCount, float64(s.CanceledAcquireCount()))
+}
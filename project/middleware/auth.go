@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"errors"
+	"myproject/project/auth"
+	"myproject/project/shared"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Authenticate проверяет заголовок Authorization: Bearer <JWT> и кладёт
+// id пользователя в контекст запроса. Без валидного токена отвечает 401.
+func Authenticate(issuer *auth.Issuer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="tasks"`)
+				shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, "authentication required")
+				return
+			}
+			claims, err := issuer.Verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="tasks", error="invalid_token"`)
+				shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, err.Error())
+				return
+			}
+			ctx := shared.ContextWithUserID(r.Context(), claims.UserID)
+			ctx = auth.ContextWithClaims(ctx, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// CurrentRole заменяет роль из токена текущей ролью пользователя из roles.
+// Ставится после Authenticate. Удалённый пользователь получает 401,
+// недоступность хранилища ролей - 503.
+func CurrentRole(roles *auth.RoleCache) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ := auth.ClaimsFromContext(r.Context())
+			role, err := roles.Role(r.Context(), claims.UserID)
+			if err != nil {
+				if errors.Is(err, auth.ErrUnknownUser) {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="tasks", error="invalid_token"`)
+					shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, err.Error())
+					return
+				}
+				shared.WriteError(w, r, http.StatusServiceUnavailable, shared.CodeUpstreamUnavailable, "cannot resolve user role")
+				return
+			}
+			claims.Role = role
+			next.ServeHTTP(w, r.WithContext(auth.ContextWithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// TrustedUser читает id пользователя, переданный api-service в заголовке
+// X-User-ID, и кладёт его в контекст запроса. X-Owner-Scope: all снимает
+// ограничение задач этим пользователем.
+func TrustedUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.Header.Get(shared.UserIDHeader))
+		if err != nil || id <= 0 {
+			shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, "missing or invalid "+shared.UserIDHeader)
+			return
+		}
+		ctx := shared.ContextWithUserID(r.Context(), id)
+		switch r.Header.Get(shared.OwnerScopeHeader) {
+		case "":
+		case shared.OwnerScopeAll:
+			ctx = shared.ContextWithAllOwners(ctx)
+		default:
+			shared.WriteError(w, r, http.StatusBadRequest, shared.CodeBadRequest, "invalid "+shared.OwnerScopeHeader)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
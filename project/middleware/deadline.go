@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"myproject/project/shared"
+
+	"github.com/gorilla/mux"
+)
+
+// Deadline ограничивает время обработки запроса дедлайном маршрута.
+// Контекст запроса отменяется и при обрыве соединения клиентом, так что
+// отмена доходит до исходящих запросов и запросов к БД.
+// Должен подключаться через Router.Use, чтобы маршрут был уже найден.
+func Deadline(timeouts shared.Timeouts) func(http.Handler) http.Handler {
+	return DeadlineFrom(func() shared.Timeouts { return timeouts })
+}
+
+// DeadlineFrom - Deadline с дедлайнами, которые current возвращает на каждый
+// запрос, например из перезагружаемого конфига.
+func DeadlineFrom(current func() shared.Timeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), current().For(r.Method, routeTemplate(r)))
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// routeTemplate возвращает шаблон найденного маршрута mux, например /tasks/{id},
+// или путь запроса, если маршрута нет.
+func routeTemplate(r *http.Request) string {
+	if cur := mux.CurrentRoute(r); cur != nil {
+		if tpl, err := cur.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
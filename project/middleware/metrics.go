@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"myproject/project/metrics"
+)
+
+// Metrics считает запросы, их длительность и число одновременно
+// обрабатываемых. Подключается через Router.Use, чтобы маршрут был уже найден.
+func Metrics(m *metrics.HTTP) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			done := m.Start()
+			rec := &StatusRecorder{w, http.StatusOK}
+			next.ServeHTTP(rec, r)
+			done(r.Method, routeTemplate(r), rec.status)
+		})
+	}
+}
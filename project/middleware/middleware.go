@@ -2,21 +2,32 @@ package middleware
 
 import (
 	"fmt"
+	"io"
+	"myproject/project/shared"
 	"net/http"
+	"os"
 	"time"
 )
 
+// LoggingMiddlware пишет строку доступа на каждый запрос в stdout.
 func LoggingMiddlware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		rec := &StatusRecorder{w, http.StatusOK}
-		next.ServeHTTP(rec, r)
-		duration := time.Since(start)
-		ip := r.RemoteAddr
-		path := r.URL.Path
-		userAgent := r.Header.Get("User-Agent")
-		fmt.Printf("[%s] IP:%s|Метод:%s|Путь:%s|Статус:%d|Время:%v|Агент:%s\n", start.Format("2006-01-02 15:04:05"), ip, r.Method, path, rec.status, duration, userAgent)
-	})
+	return AccessLog(os.Stdout)(next)
+}
+
+// AccessLog пишет строку доступа на каждый запрос в w, например в отдельный файл.
+func AccessLog(w io.Writer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &StatusRecorder{rw, http.StatusOK}
+			next.ServeHTTP(rec, r)
+			duration := time.Since(start)
+			ip := r.RemoteAddr
+			path := r.URL.Path
+			userAgent := r.Header.Get("User-Agent")
+			fmt.Fprintf(w, "[%s] ReqID:%s|IP:%s|Метод:%s|Путь:%s|Статус:%d|Время:%v|Агент:%s\n", start.Format("2006-01-02 15:04:05"), shared.RequestIDFromContext(r.Context()), ip, r.Method, path, rec.status, duration, userAgent)
+		})
+	}
 }
 
 type StatusRecorder struct {
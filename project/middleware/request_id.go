@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"myproject/project/shared"
+)
+
+// RequestID принимает корректный X-Request-ID клиента или создаёт новый,
+// кладёт его в контекст запроса и возвращает в заголовке ответа.
+// Должен подключаться первым, чтобы идентификатор был у всех последующих слоёв.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(shared.RequestIDHeader)
+		if !shared.ValidRequestID(id) {
+			id = shared.NewRequestID()
+		}
+		w.Header().Set(shared.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(shared.ContextWithRequestID(r.Context(), id)))
+	})
+}
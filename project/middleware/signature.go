@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	logger "myproject/project/Logger"
+	"myproject/project/auth"
+	"myproject/project/shared"
+	"net/http"
+	"time"
+)
+
+// maxSignedBody ограничивает размер тела, которое читается для проверки подписи.
+const maxSignedBody = 10 << 20
+
+// VerifySignature пропускает только запросы, подписанные общим секретом
+// api-service. Подписи старше window и повторно использованные nonce отклоняются.
+func VerifySignature(signer *auth.Signer, window time.Duration, log *logger.Logger) func(http.Handler) http.Handler {
+	nonces := auth.NewNonceCache(window)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(r.Context())
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBody))
+			if err != nil {
+				log.ERROR(fmt.Sprintf("VerifySignature: failed to read body: %v", err))
+				shared.WriteError(w, r, http.StatusRequestEntityTooLarge, shared.CodePayloadTooLarge, "request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			if err := signer.Verify(r, body, now, window); err != nil {
+				log.ERROR(fmt.Sprintf("VerifySignature: %s %s from %s rejected: %v", r.Method, r.URL.Path, r.RemoteAddr, err))
+				shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, err.Error())
+				return
+			}
+			if !nonces.Use(r.Header.Get(auth.HeaderNonce), now) {
+				log.ERROR(fmt.Sprintf("VerifySignature: %s %s from %s rejected: %v", r.Method, r.URL.Path, r.RemoteAddr, auth.ErrSignatureReplay))
+				shared.WriteError(w, r, http.StatusUnauthorized, shared.CodeUnauthorized, auth.ErrSignatureReplay.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
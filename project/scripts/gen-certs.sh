@@ -0,0 +1,27 @@
+#!/bin/sh
+# Генерирует локальные сертификаты для mTLS между api-service и db-service:
+# CA, серверный сертификат db-service (localhost) и клиентский для api-service.
+# Использование: ./gen-certs.sh [каталог] (по умолчанию ./certs)
+set -eu
+
+dir="${1:-certs}"
+mkdir -p "$dir"
+cd "$dir"
+
+openssl req -x509 -newkey rsa:2048 -nodes -days 365 \
+	-keyout ca.key -out ca.crt -subj "/CN=task-manager-ca"
+
+openssl req -newkey rsa:2048 -nodes \
+	-keyout db-service.key -out db-service.csr -subj "/CN=localhost"
+printf "subjectAltName=DNS:localhost,IP:127.0.0.1\nextendedKeyUsage=serverAuth\n" > db-service.ext
+openssl x509 -req -in db-service.csr -CA ca.crt -CAkey ca.key -CAcreateserial \
+	-days 365 -out db-service.crt -extfile db-service.ext
+
+openssl req -newkey rsa:2048 -nodes \
+	-keyout api-service.key -out api-service.csr -subj "/CN=api-service"
+printf "extendedKeyUsage=clientAuth\n" > api-service.ext
+openssl x509 -req -in api-service.csr -CA ca.crt -CAkey ca.key -CAcreateserial \
+	-days 365 -out api-service.crt -extfile api-service.ext
+
+rm -f ./*.csr ./*.ext ca.srl
+echo "certificates written to $(pwd)"
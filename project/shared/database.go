@@ -0,0 +1,37 @@
+package shared
+
+import "time"
+
+// PoolConfig - пул соединений с PostgreSQL. Нулевые значения оставляют
+// настройки pgx по умолчанию (или заданные в DSN).
+type PoolConfig struct {
+	MaxConns int32 `yaml:"max_conns" env:"DB_POOL_MAX_CONNS"`
+	MinConns int32 `yaml:"min_conns" env:"DB_POOL_MIN_CONNS"`
+	// MaxConnLifetime - через сколько соединение закрывается и открывается заново
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime"`
+	// MaxConnIdleTime - сколько неиспользуемое соединение остаётся открытым
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"`
+	// HealthCheckPeriod - как часто пул проверяет простаивающие соединения
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+	// StatementTimeout - statement_timeout сессии: PostgreSQL прерывает запросы дольше него
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+	// ApplicationName - application_name сессии, виден в pg_stat_activity
+	ApplicationName string `yaml:"application_name"`
+	// SearchPath - search_path сессии, например "tasks, public"
+	SearchPath string `yaml:"search_path"`
+	// ConnectTimeout - сколько при запуске ждать, пока БД станет доступна
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+}
+
+// UniquenessRules - необязательные правила уникальности задач, по умолчанию
+// выключены. Каждое правило - уникальный индекс из одноимённой необязательной
+// миграции db-service: migrate up создаёт его после включения правила и удаляет
+// после выключения. Нарушение правила возвращается как 409 с id существующей задачи.
+type UniquenessRules struct {
+	OpenTitlePerOwner bool `yaml:"open_title_per_owner"`
+}
+
+// Migrations возвращает включённость правил по именам необязательных миграций.
+func (u UniquenessRules) Migrations() map[string]bool {
+	return map[string]bool{"open_title_per_owner": u.OpenTitlePerOwner}
+}
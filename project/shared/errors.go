@@ -0,0 +1,109 @@
+package shared
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса.
+const RequestIDHeader = "X-Request-ID"
+
+// Машинные коды ошибок ErrorResponse.Code.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeValidation           = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeInternal             = "internal"
+	CodeUpstreamUnavailable  = "upstream_unavailable"
+	CodeUpstreamTimeout      = "upstream_timeout"
+)
+
+// ErrorResponse - тело любого ответа с ошибкой в обоих сервисах.
+type ErrorResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+func (e ErrorResponse) Error() string {
+	return e.Message
+}
+
+// CodeForStatus - код ошибки по умолчанию для HTTP-статуса.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return CodeIdempotencyKeyReused
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return CodeUpstreamUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeUpstreamTimeout
+	}
+	return CodeInternal
+}
+
+// WriteError отвечает ErrorResponse со статусом status.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteErrorResponse(w, r, status, ErrorResponse{Code: code, Message: message})
+}
+
+// WriteInternalError отвечает 500 с общим сообщением. Причину вызывающий
+// пишет в лог: её текст не должен попадать к клиенту.
+func WriteInternalError(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// WriteErrorResponse отвечает resp, дополняя его идентификатором запроса.
+func WriteErrorResponse(w http.ResponseWriter, r *http.Request, status int, resp ErrorResponse) {
+	if resp.Code == "" {
+		resp.Code = CodeForStatus(status)
+	}
+	if resp.RequestID == "" {
+		resp.RequestID = RequestIDFromContext(r.Context())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// NotFoundHandler и MethodNotAllowedHandler заменяют текстовые ответы mux по умолчанию.
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, http.StatusNotFound, CodeNotFound, "route not found")
+	})
+}
+
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	})
+}
@@ -0,0 +1,247 @@
+package shared
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+	DefaultSort      = "-created_at"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Допустимые поля сортировки. Префикс "-" означает порядок по убыванию.
+var sortFields = map[string]bool{
+	"created_at": true,
+	"id":         true,
+	"title":      true,
+}
+
+// TaskFilter описывает параметры выборки для GET /tasks.
+type TaskFilter struct {
+	Limit         int
+	Offset        int
+	Cursor        string
+	Status        *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string
+	// OwnerID оставляет только задачи этого владельца; 0 - задачи всех владельцев
+	OwnerID int
+	// Trashed выбирает удалённые задачи вместо активных (GET /tasks/trash).
+	// Задаётся маршрутом, а не query-параметром.
+	Trashed bool
+}
+
+// TaskPage - одна страница списка задач.
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+// Cursor - позиция последней отданной строки для keyset-пагинации.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+// SortField возвращает имя колонки и направление сортировки.
+func (f TaskFilter) SortField() (field string, desc bool) {
+	sort := f.Sort
+	if sort == "" {
+		sort = DefaultSort
+	}
+	return strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+}
+
+// ParseTaskFilter разбирает query-параметры запроса и проверяет их.
+func ParseTaskFilter(q url.Values) (TaskFilter, error) {
+	f := TaskFilter{Limit: DefaultPageLimit, Sort: DefaultSort}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidFilter)
+		}
+		if n > MaxPageLimit {
+			n = MaxPageLimit
+		}
+		f.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("%w: offset must be a non-negative integer", ErrInvalidFilter)
+		}
+		f.Offset = n
+	}
+	if v := q.Get("sort"); v != "" {
+		if !sortFields[strings.TrimPrefix(v, "-")] {
+			return f, fmt.Errorf("%w: unsupported sort %q", ErrInvalidFilter, v)
+		}
+		f.Sort = v
+	}
+	if v := q.Get("cursor"); v != "" {
+		if f.Offset > 0 {
+			return f, fmt.Errorf("%w: cursor and offset cannot be combined", ErrInvalidFilter)
+		}
+		c, err := DecodeCursor(v)
+		if err != nil {
+			return f, err
+		}
+		if c.Sort != f.Sort {
+			return f, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidFilter, c.Sort)
+		}
+		f.Cursor = v
+	}
+	switch v := q.Get("status"); v {
+	case "":
+	case "done":
+		done := true
+		f.Status = &done
+	case "open":
+		open := false
+		f.Status = &open
+	default:
+		return f, fmt.Errorf("%w: status must be done or open", ErrInvalidFilter)
+	}
+	if v := q.Get("owner"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("%w: owner must be a positive integer", ErrInvalidFilter)
+		}
+		f.OwnerID = n
+	}
+	if v := q.Get("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("%w: created_after must be RFC3339", ErrInvalidFilter)
+		}
+		f.CreatedAfter = &t
+	}
+	if v := q.Get("created_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("%w: created_before must be RFC3339", ErrInvalidFilter)
+		}
+		f.CreatedBefore = &t
+	}
+	return f, nil
+}
+
+// Values собирает фильтр обратно в query-параметры для запроса к db-service.
+func (f TaskFilter) Values() url.Values {
+	q := url.Values{}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Offset > 0 {
+		q.Set("offset", strconv.Itoa(f.Offset))
+	}
+	if f.Cursor != "" {
+		q.Set("cursor", f.Cursor)
+	}
+	if f.Status != nil {
+		if *f.Status {
+			q.Set("status", "done")
+		} else {
+			q.Set("status", "open")
+		}
+	}
+	if f.OwnerID > 0 {
+		q.Set("owner", strconv.Itoa(f.OwnerID))
+	}
+	if f.CreatedAfter != nil {
+		q.Set("created_after", f.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if f.CreatedBefore != nil {
+		q.Set("created_before", f.CreatedBefore.Format(time.RFC3339Nano))
+	}
+	if f.Sort != "" {
+		q.Set("sort", f.Sort)
+	}
+	return q
+}
+
+// SearchQuery - параметры GET /tasks/search.
+type SearchQuery struct {
+	Q      string
+	Limit  int
+	Offset int
+}
+
+type SearchResult struct {
+	Task    Task    `json:"task"`
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+func ParseSearchQuery(q url.Values) (SearchQuery, error) {
+	s := SearchQuery{Q: strings.TrimSpace(q.Get("q")), Limit: DefaultPageLimit}
+	if s.Q == "" {
+		return s, fmt.Errorf("%w: q is required", ErrInvalidFilter)
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return s, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidFilter)
+		}
+		if n > MaxPageLimit {
+			n = MaxPageLimit
+		}
+		s.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return s, fmt.Errorf("%w: offset must be a non-negative integer", ErrInvalidFilter)
+		}
+		s.Offset = n
+	}
+	return s, nil
+}
+
+func (s SearchQuery) Values() url.Values {
+	q := url.Values{}
+	q.Set("q", s.Q)
+	if s.Limit > 0 {
+		q.Set("limit", strconv.Itoa(s.Limit))
+	}
+	if s.Offset > 0 {
+		q.Set("offset", strconv.Itoa(s.Offset))
+	}
+	return q
+}
@@ -0,0 +1,110 @@
+package shared
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTaskFilter(t *testing.T) {
+	done, open := true, false
+	after := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cursor := EncodeCursor(Cursor{Sort: "id", Value: "10", ID: 10})
+
+	tests := []struct {
+		name    string
+		query   string
+		want    TaskFilter
+		wantErr bool
+	}{
+		{name: "defaults", query: "", want: TaskFilter{Limit: DefaultPageLimit, Sort: DefaultSort}},
+		{name: "limit and offset", query: "limit=10&offset=20", want: TaskFilter{Limit: 10, Offset: 20, Sort: DefaultSort}},
+		{name: "limit capped", query: "limit=100000", want: TaskFilter{Limit: MaxPageLimit, Sort: DefaultSort}},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "non-numeric limit", query: "limit=ten", wantErr: true},
+		{name: "negative offset", query: "offset=-1", wantErr: true},
+		{name: "sort ascending", query: "sort=title", want: TaskFilter{Limit: DefaultPageLimit, Sort: "title"}},
+		{name: "sort descending", query: "sort=-id", want: TaskFilter{Limit: DefaultPageLimit, Sort: "-id"}},
+		{name: "unsupported sort", query: "sort=description", wantErr: true},
+		{name: "status done", query: "status=done", want: TaskFilter{Limit: DefaultPageLimit, Sort: DefaultSort, Status: &done}},
+		{name: "status open", query: "status=open", want: TaskFilter{Limit: DefaultPageLimit, Sort: DefaultSort, Status: &open}},
+		{name: "unknown status", query: "status=maybe", wantErr: true},
+		{name: "created after", query: "created_after=2024-01-02T03:04:05Z", want: TaskFilter{Limit: DefaultPageLimit, Sort: DefaultSort, CreatedAfter: &after}},
+		{name: "created before not RFC3339", query: "created_before=2024-01-02", wantErr: true},
+		{name: "owner", query: "owner=7", want: TaskFilter{Limit: DefaultPageLimit, Sort: DefaultSort, OwnerID: 7}},
+		{name: "zero owner", query: "owner=0", wantErr: true},
+		{name: "cursor", query: "sort=id&cursor=" + cursor, want: TaskFilter{Limit: DefaultPageLimit, Sort: "id", Cursor: cursor}},
+		{name: "cursor for another sort", query: "cursor=" + cursor, wantErr: true},
+		{name: "cursor with offset", query: "sort=id&offset=5&cursor=" + cursor, wantErr: true},
+		{name: "malformed cursor", query: "cursor=%21%21", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("bad test query: %v", err)
+			}
+			got, err := ParseTaskFilter(q)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFilter) {
+					t.Fatalf("err = %v, want ErrInvalidFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskFilterValuesRoundTrip(t *testing.T) {
+	done := false
+	before := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	after := time.Date(2024, 5, 6, 7, 8, 9, 123_456_789, time.UTC)
+	filters := []TaskFilter{
+		{Limit: DefaultPageLimit, Sort: DefaultSort},
+		{Limit: 5, Offset: 15, Sort: "title", Status: &done, CreatedBefore: &before, OwnerID: 3},
+		{Limit: 5, Sort: "created_at", CreatedAfter: &after, CreatedBefore: &before},
+		{Limit: 5, Sort: "-id", Cursor: EncodeCursor(Cursor{Sort: "-id", Value: "42", ID: 42})},
+	}
+	for _, f := range filters {
+		got, err := ParseTaskFilter(f.Values())
+		if err != nil {
+			t.Fatalf("ParseTaskFilter(%v): %v", f.Values(), err)
+		}
+		if !reflect.DeepEqual(got, f) {
+			t.Errorf("round trip: got %+v, want %+v", got, f)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []Cursor{
+		{Sort: DefaultSort, Value: "2024-01-02T03:04:05.123456Z", ID: 1},
+		{Sort: "title", Value: "строка с \"кавычками\" и пробелами", ID: 99},
+		{Sort: "id", Value: "", ID: 0},
+	}
+	for _, c := range cursors {
+		got, err := DecodeCursor(EncodeCursor(c))
+		if err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		if got != c {
+			t.Errorf("got %+v, want %+v", got, c)
+		}
+	}
+}
+
+func TestDecodeCursorMalformed(t *testing.T) {
+	for _, s := range []string{"!!", "bm90IGpzb24", "e30=x"} {
+		if _, err := DecodeCursor(s); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("DecodeCursor(%q) err = %v, want ErrInvalidFilter", s, err)
+		}
+	}
+}
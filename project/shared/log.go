@@ -0,0 +1,32 @@
+package shared
+
+import "time"
+
+// LogConfig - настройки logger.Logger.
+type LogConfig struct {
+	// Level - минимальный уровень: debug, info, warn, error (по умолчанию info)
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// Format - text или json (по умолчанию text)
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	// Redact - ключи полей, значения которых заменяются на [REDACTED].
+	// Если не задан, используется logger.DefaultRedact.
+	Redact []string `yaml:"redact"`
+	// AddSource добавляет в запись файл и строку вызова
+	AddSource bool `yaml:"add_source"`
+	// File - файл лога вместо stdout
+	File FileSinkConfig `yaml:"file"`
+	// AccessLog - отдельный файл для строк доступа HTTP (middleware.AccessLog)
+	AccessLog FileSinkConfig `yaml:"access_log"`
+}
+
+// FileSinkConfig - файл лога с ротацией. Пустой Path - запись в stdout.
+type FileSinkConfig struct {
+	Path string `yaml:"path"`
+	// MaxSizeMB - ротация, когда файл превысит этот размер; 0 - без ограничения
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// MaxAge - ротация, когда файл пишется дольше этого времени; 0 - без ограничения
+	MaxAge time.Duration `yaml:"max_age"`
+	// MaxBackups - сколько ротированных файлов хранить; 0 - все
+	MaxBackups int  `yaml:"max_backups"`
+	Compress   bool `yaml:"compress"`
+}
@@ -1,6 +1,12 @@
 package shared
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Task struct {
 	ID          int
@@ -8,6 +14,9 @@ type Task struct {
 	Description string
 	Status      bool
 	Created_at  time.Time
+	Version     int
+	Deleted_at  *time.Time
+	Owner_id    int
 }
 type IDResponse struct {
 	ID int64 `json:"id"`
@@ -16,12 +25,93 @@ type PostResponse struct {
 	Message string `json:"message"`
 	ID      int64  `json:"id"`
 }
+
 type DeleteOrUpdateResponse struct {
 	Message    string `json:"message"`
 	StatusCode int    `json:"status"`
 }
-type Config struct {
-	DBService struct {
-		URL string `yaml:"url"`
-	} `yaml:"db_service"`
+
+// TaskPatch - частичное обновление задачи (PATCH /tasks/{id}).
+// Поля со значением nil не изменяются.
+type TaskPatch struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Status      *bool   `json:"status,omitempty"`
+}
+
+func (p TaskPatch) IsEmpty() bool {
+	return p.Title == nil && p.Description == nil && p.Status == nil
+}
+
+// ETag формирует сильный ETag по версии задачи.
+func ETag(version int) string {
+	return fmt.Sprintf("%q", strconv.Itoa(version))
 }
+
+// ErrWeakETag - в If-Match передан слабый ETag. If-Match сравнивает теги
+// строго (RFC 9110, 13.1.1), слабый тег ни с чем не совпадает.
+var ErrWeakETag = errors.New("weak ETag in If-Match")
+
+// ParseETag извлекает версию из значения If-Match.
+// Для "*" и пустой строки возвращается 0 - условие не проверяется.
+func ParseETag(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(s, "W/") {
+		return 0, fmt.Errorf("%w: %s", ErrWeakETag, s)
+	}
+	v, err := strconv.Atoi(strings.Trim(s, `"`))
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("malformed ETag %s", s)
+	}
+	return v, nil
+}
+
+const (
+	// BatchAtomic - все элементы пакета применяются в одной транзакции или ни один.
+	BatchAtomic = "atomic"
+	// BatchPartial - каждый элемент применяется независимо, ответ содержит результат по каждому.
+	BatchPartial = "partial"
+
+	MaxBatchSize = 1000
+)
+
+// BatchPatch - элемент PATCH /tasks/batch.
+type BatchPatch struct {
+	ID int `json:"id"`
+	TaskPatch
+}
+
+// BatchResult - результат обработки одного элемента пакета.
+type BatchResult struct {
+	Index  int `json:"index"`
+	ID     int `json:"id,omitempty"`
+	Status int `json:"status"`
+	// Code - машинный код ошибки элемента, как в ErrorResponse
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+	Task  *Task  `json:"task,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode    string        `json:"mode"`
+	Results []BatchResult `json:"results"`
+}
+
+func ParseBatchMode(s string) (string, error) {
+	switch s {
+	case "", BatchAtomic:
+		return BatchAtomic, nil
+	case BatchPartial:
+		return BatchPartial, nil
+	}
+	return "", fmt.Errorf("mode must be %s or %s", BatchAtomic, BatchPartial)
+}
+
+// IdempotencyKeyHeader - заголовок, по которому повтор POST /tasks
+// возвращает исходный ответ вместо создания дубликата.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const MaxIdempotencyKeyLen = 255
@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "*", want: 0},
+		{in: "  *  ", want: 0},
+		{in: `"3"`, want: 3},
+		{in: `W/"12"`, wantErr: true},
+		{in: ` "7" `, want: 7},
+		{in: "5", want: 5},
+		{in: `"0"`, wantErr: true},
+		{in: `"-1"`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `""`, wantErr: true},
+		{in: `"1", "2"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseETag(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseETag(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseETag(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseETagWeak(t *testing.T) {
+	if _, err := ParseETag(`W/"12"`); !errors.Is(err, ErrWeakETag) {
+		t.Fatalf("err = %v, want ErrWeakETag", err)
+	}
+}
+
+func TestETagRoundTrip(t *testing.T) {
+	for _, v := range []int{1, 2, 1000} {
+		got, err := ParseETag(ETag(v))
+		if err != nil || got != v {
+			t.Errorf("ParseETag(ETag(%d)) = %d, %v", v, got, err)
+		}
+	}
+}
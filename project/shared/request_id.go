@@ -0,0 +1,45 @@
+package shared
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// MaxRequestIDLen - входящие X-Request-ID длиннее этого заменяются новыми.
+const MaxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext возвращает идентификатор запроса или "", если его нет.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID принимает непустые идентификаторы из букв, цифр и символов -_.:
+// чтобы чужой заголовок не мог подделать строки логов.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package shared
+
+import "time"
+
+// RetryConfig - повторы идемпотентных запросов к db-service.
+// MaxAttempts = 1 отключает повторы.
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	BaseDelay   time.Duration `yaml:"base_delay"`
+	MaxDelay    time.Duration `yaml:"max_delay"`
+}
+
+func (c RetryConfig) WithDefaults() RetryConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = 100 * time.Millisecond
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = 2 * time.Second
+	}
+	return c
+}
+
+// BreakerConfig - circuit breaker: после FailureThreshold ошибок подряд
+// запросы к upstream не выполняются в течение OpenTimeout.
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	OpenTimeout      time.Duration `yaml:"open_timeout"`
+}
+
+func (c BreakerConfig) WithDefaults() BreakerConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	return c
+}
@@ -0,0 +1,36 @@
+package shared
+
+import "time"
+
+// ServerConfig - таймауты http.Server и порядок остановки.
+type ServerConfig struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	// WriteTimeout должен быть больше самого длинного дедлайна в timeouts
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// DrainDelay - пауза между переводом /readyz в fail и закрытием слушателя,
+	// чтобы балансировщик успел перестать присылать запросы
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// ShutdownTimeout - сколько ждать завершения уже принятых запросов
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+func (c ServerConfig) WithDefaults() ServerConfig {
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = 30 * time.Second
+	}
+	if c.ReadHeaderTimeout <= 0 {
+		c.ReadHeaderTimeout = 5 * time.Second
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 60 * time.Second
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 120 * time.Second
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = 30 * time.Second
+	}
+	return c
+}
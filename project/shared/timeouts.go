@@ -0,0 +1,25 @@
+package shared
+
+import "time"
+
+// DefaultRequestTimeout используется, если в конфиге не задан timeouts.default.
+const DefaultRequestTimeout = 10 * time.Second
+
+// Timeouts - дедлайны обработки запросов.
+// Ключ Routes - метод и шаблон маршрута mux, например "GET /tasks/{id}".
+type Timeouts struct {
+	Default time.Duration            `yaml:"default"`
+	Routes  map[string]time.Duration `yaml:"routes"`
+}
+
+// For возвращает дедлайн для маршрута: сначала ищется точное совпадение,
+// затем берётся Default, затем DefaultRequestTimeout.
+func (t Timeouts) For(method, route string) time.Duration {
+	if d, ok := t.Routes[method+" "+route]; ok && d > 0 {
+		return d
+	}
+	if t.Default > 0 {
+		return t.Default
+	}
+	return DefaultRequestTimeout
+}
@@ -0,0 +1,24 @@
+package shared
+
+// Экспортёры трассировки.
+const (
+	TraceExporterNone   = "none"
+	TraceExporterOTLP   = "otlp"
+	TraceExporterStdout = "stdout"
+	TraceExporterFile   = "file"
+)
+
+// TracingConfig - настройки OpenTelemetry. Пустой Exporter равен none:
+// спаны не записываются, но контекст трассировки передаётся дальше.
+type TracingConfig struct {
+	// Exporter - none, otlp, stdout или file
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	// Endpoint - адрес OTLP/HTTP коллектора, например localhost:4318
+	Endpoint string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	// Insecure отключает TLS при отправке в коллектор
+	Insecure bool `yaml:"insecure"`
+	// File - файл для экспортёра file (JSON, по спану на строку)
+	File string `yaml:"file"`
+	// SampleRatio - доля записываемых трасс от 0 до 1; 0 означает 1
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
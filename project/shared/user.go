@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"context"
+	"time"
+)
+
+// UserIDHeader передаёт id аутентифицированного пользователя из api-service в db-service.
+const UserIDHeader = "X-User-ID"
+
+// OwnerScopeHeader со значением OwnerScopeAll снимает ограничение запросов
+// к задачам владельцем из UserIDHeader. api-service ставит его только
+// для ролей с правом auth.ActionAllOwners.
+const (
+	OwnerScopeHeader = "X-Owner-Scope"
+	OwnerScopeAll    = "all"
+)
+
+type User struct {
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`
+	Role       string    `json:"role"`
+	Created_at time.Time `json:"created_at"`
+}
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+type userIDKey struct{}
+
+func ContextWithUserID(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// UserIDFromContext возвращает id пользователя, от имени которого выполняется запрос.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(userIDKey{}).(int)
+	return id, ok && id > 0
+}
+
+type allOwnersKey struct{}
+
+// ContextWithAllOwners разрешает запросу доступ к задачам всех владельцев.
+func ContextWithAllOwners(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allOwnersKey{}, true)
+}
+
+func AllOwnersFromContext(ctx context.Context) bool {
+	all, _ := ctx.Value(allOwnersKey{}).(bool)
+	return all
+}